@@ -0,0 +1,190 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+type TargetDeviation struct {
+	Target       float64 `json:"target"`
+	Actual       float64 `json:"actual"`
+	Deviation    float64 `json:"deviation"`
+	DeviationPct float64 `json:"deviation_pct"`
+	Within       bool    `json:"within"`
+}
+
+type DayAdherence struct {
+	Date      string           `json:"date"`
+	Calories  *TargetDeviation `json:"calories,omitempty"`
+	Protein   *TargetDeviation `json:"protein,omitempty"`
+	Fat       *TargetDeviation `json:"fat,omitempty"`
+	Carbs     *TargetDeviation `json:"carbs,omitempty"`
+	Compliant bool             `json:"compliant"`
+	Score     float64          `json:"score"`
+}
+
+type PeriodAdherence struct {
+	Start          string  `json:"start"`
+	End            string  `json:"end"`
+	DaysLogged     int     `json:"days_logged"`
+	CompliantDays  int     `json:"compliant_days"`
+	ComplianceRate float64 `json:"compliance_rate"`
+	Score          float64 `json:"score"`
+}
+
+type AdherenceReport struct {
+	Username      string            `json:"username"`
+	Targets       scraper.Targets   `json:"targets"`
+	TargetSource  string            `json:"target_source"`
+	Days          []DayAdherence    `json:"days"`
+	Periods       []PeriodAdherence `json:"periods"`
+	Overall       PeriodAdherence   `json:"overall"`
+	CurrentStreak int               `json:"current_streak"`
+	LongestStreak int               `json:"longest_streak"`
+}
+
+func deviation(target, actual, tolerance float64) *TargetDeviation {
+	if target <= 0 {
+		return nil
+	}
+
+	d := &TargetDeviation{
+		Target:    target,
+		Actual:    actual,
+		Deviation: actual - target,
+	}
+	d.DeviationPct = d.Deviation / target * 100
+	d.Within = math.Abs(d.DeviationPct) <= tolerance
+
+	return d
+}
+
+// dayScore is 100 on target and loses two points per percent of deviation,
+// averaged over the targets that are set.
+func dayScore(deviations ...*TargetDeviation) (float64, bool) {
+	total := 0.0
+	count := 0
+	compliant := true
+
+	for _, d := range deviations {
+		if d == nil {
+			continue
+		}
+		total += math.Max(0, 100-2*math.Abs(d.DeviationPct))
+		count++
+		compliant = compliant && d.Within
+	}
+
+	if count == 0 {
+		return 0, false
+	}
+
+	return total / float64(count), compliant
+}
+
+// TargetsFor returns the user's configured targets or, failing that, the
+// calorie goal FatSecret reported on the most recent entry that has one.
+func TargetsFor(user scraper.User, entries []scraper.DiaryEntry) (scraper.Targets, string) {
+	if user.Targets != nil {
+		return user.Targets.Resolve(), "user"
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Summary == nil {
+			continue
+		}
+		if goal := scraper.ParseNumber(entries[i].Summary.CalorieGoal); goal > 0 {
+			return scraper.Targets{Calories: goal}.Resolve(), "fatsecret"
+		}
+	}
+
+	return scraper.Targets{}, "none"
+}
+
+func periodAdherence(start, end time.Time, days []DayAdherence) PeriodAdherence {
+	adherence := PeriodAdherence{
+		Start:      start.Format("2006-01-02"),
+		End:        end.Format("2006-01-02"),
+		DaysLogged: len(days),
+	}
+
+	for _, day := range days {
+		adherence.Score += day.Score
+		if day.Compliant {
+			adherence.CompliantDays++
+		}
+	}
+
+	if len(days) > 0 {
+		adherence.Score /= float64(len(days))
+		adherence.ComplianceRate = float64(adherence.CompliantDays) / float64(len(days))
+	}
+
+	return adherence
+}
+
+// ComputeAdherence scores every logged day against the user's targets and
+// summarizes them per period. Streaks count consecutive compliant calendar
+// days, so a day without logging breaks a streak.
+func ComputeAdherence(user scraper.User, period Period, from, to time.Time, entries []scraper.DiaryEntry) AdherenceReport {
+	logged := LoggedEntries(entries)
+	targets, source := TargetsFor(user, logged)
+
+	report := AdherenceReport{
+		Username:     user.Username,
+		Targets:      targets,
+		TargetSource: source,
+		Days:         []DayAdherence{},
+		Periods:      []PeriodAdherence{},
+	}
+
+	byPeriod := make(map[time.Time][]DayAdherence)
+	starts := []time.Time{}
+
+	streak := 0
+	var previous time.Time
+	for _, entry := range logged {
+		date, _ := EntryDate(entry)
+
+		tolerance := targets.TolerancePct()
+		day := DayAdherence{
+			Date:     date.Format("2006-01-02"),
+			Calories: deviation(targets.Calories, scraper.ParseNumber(entry.Calories), tolerance),
+			Protein:  deviation(targets.ProteinG, scraper.ParseNumber(entry.Protein), tolerance),
+			Fat:      deviation(targets.FatG, scraper.ParseNumber(entry.Fat), tolerance),
+			Carbs:    deviation(targets.CarbsG, scraper.ParseNumber(entry.Carbs), tolerance),
+		}
+		day.Score, day.Compliant = dayScore(day.Calories, day.Protein, day.Fat, day.Carbs)
+		report.Days = append(report.Days, day)
+
+		switch {
+		case !day.Compliant:
+			streak = 0
+		case streak > 0 && date.Sub(previous) == 24*time.Hour:
+			streak++
+		default:
+			streak = 1
+		}
+		report.LongestStreak = max(report.LongestStreak, streak)
+		previous = date
+
+		start := PeriodStart(period, date)
+		if _, ok := byPeriod[start]; !ok {
+			starts = append(starts, start)
+		}
+		byPeriod[start] = append(byPeriod[start], day)
+	}
+
+	if !previous.IsZero() && !previous.Before(to.AddDate(0, 0, -1)) {
+		report.CurrentStreak = streak
+	}
+
+	for _, start := range starts {
+		report.Periods = append(report.Periods, periodAdherence(start, PeriodEnd(period, start), byPeriod[start]))
+	}
+	report.Overall = periodAdherence(from, to, report.Days)
+
+	return report
+}
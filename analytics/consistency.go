@@ -0,0 +1,173 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+type MealCoverage struct {
+	Meal        scraper.MealSlot `json:"meal"`
+	DaysLogged  int              `json:"days_logged"`
+	DaysSkipped int              `json:"days_skipped"`
+	Coverage    float64          `json:"coverage"`
+}
+
+type ConsistencyReport struct {
+	Username      string         `json:"username"`
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	TotalDays     int            `json:"total_days"`
+	DaysLogged    int            `json:"days_logged"`
+	DaysMissed    int            `json:"days_missed"`
+	LoggingRate   float64        `json:"logging_rate"`
+	CurrentStreak int            `json:"current_streak"`
+	LongestStreak int            `json:"longest_streak"`
+	AverageMeals  float64        `json:"average_meals_per_day"`
+	MealCoverage  []MealCoverage `json:"meal_coverage"`
+	MissedDates   []string       `json:"missed_dates"`
+	FirstLogged   string         `json:"first_logged,omitempty"`
+	LastLogged    string         `json:"last_logged,omitempty"`
+	FirstLoggedAt string         `json:"first_logged_at,omitempty"`
+	LastLoggedAt  string         `json:"last_logged_at,omitempty"`
+	LastScraped   string         `json:"last_scraped,omitempty"`
+}
+
+// ComputeConsistency reports how regularly the user logged between from and
+// to. A meal counts as logged on a day when it has at least one item.
+func ComputeConsistency(username string, from, to time.Time, entries []scraper.DiaryEntry) ConsistencyReport {
+	report := ConsistencyReport{
+		Username:     username,
+		From:         from.Format("2006-01-02"),
+		To:           to.Format("2006-01-02"),
+		MealCoverage: []MealCoverage{},
+		MissedDates:  []string{},
+	}
+
+	logged := make(map[time.Time]scraper.DiaryEntry)
+	for _, entry := range LoggedEntries(entries) {
+		date, _ := EntryDate(entry)
+		logged[date] = entry
+	}
+
+	mealDays := make(map[scraper.MealSlot]int)
+	totalMeals := 0
+
+	streak := 0
+	var lastScraped time.Time
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		report.TotalDays++
+
+		entry, ok := logged[date]
+		if !ok {
+			report.MissedDates = append(report.MissedDates, date.Format("2006-01-02"))
+			streak = 0
+			continue
+		}
+
+		report.DaysLogged++
+		streak++
+		report.LongestStreak = max(report.LongestStreak, streak)
+
+		if report.FirstLogged == "" {
+			report.FirstLogged = date.Format("2006-01-02")
+		}
+		report.LastLogged = date.Format("2006-01-02")
+
+		if scraped, err := time.Parse("02/01/2006", entry.Timestamp); err == nil && scraped.After(lastScraped) {
+			lastScraped = scraped
+		}
+
+		slots := make(map[scraper.MealSlot]bool)
+		for _, meal := range entry.Meals {
+			if len(meal.Items) == 0 {
+				continue
+			}
+			slots[scraper.CanonicalMealSlot(meal.Name)] = true
+			totalMeals++
+		}
+		for slot := range slots {
+			mealDays[slot]++
+		}
+	}
+
+	report.DaysMissed = report.TotalDays - report.DaysLogged
+	if report.TotalDays > 0 {
+		report.LoggingRate = float64(report.DaysLogged) / float64(report.TotalDays)
+	}
+	if report.DaysLogged > 0 {
+		report.AverageMeals = float64(totalMeals) / float64(report.DaysLogged)
+	}
+
+	// Today may simply not be logged yet, so it doesn't end the current streak.
+	report.CurrentStreak = streakEndingAt(logged, to, from)
+	if report.CurrentStreak == 0 {
+		report.CurrentStreak = streakEndingAt(logged, to.AddDate(0, 0, -1), from)
+	}
+
+	for _, meal := range scraper.MealSlots {
+		if mealDays[meal] == 0 && meal == scraper.SlotOther {
+			continue
+		}
+		coverage := MealCoverage{
+			Meal:        meal,
+			DaysLogged:  mealDays[meal],
+			DaysSkipped: report.DaysLogged - mealDays[meal],
+		}
+		if report.DaysLogged > 0 {
+			coverage.Coverage = float64(coverage.DaysLogged) / float64(report.DaysLogged)
+		}
+		report.MealCoverage = append(report.MealCoverage, coverage)
+	}
+
+	if !lastScraped.IsZero() {
+		report.LastScraped = lastScraped.Format("2006-01-02")
+	}
+
+	return report
+}
+
+// AddLoggedTimes fills in FirstLoggedAt and LastLoggedAt from the diary
+// version history. FatSecret doesn't show when a food was logged, so these
+// are the times the scraper first saw food on the first logged day and last
+// saw a change on the last one, only as precise as the scrape schedule.
+// They stay empty for days scraped before history was kept.
+func AddLoggedTimes(report *ConsistencyReport, user scraper.User) error {
+	if report.FirstLogged == "" {
+		return nil
+	}
+
+	first, _ := time.Parse("2006-01-02", report.FirstLogged)
+	versions, err := scraper.LoadDiaryVersions(user, first)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		if len(LoggedEntries([]scraper.DiaryEntry{version.Entry})) > 0 {
+			report.FirstLoggedAt = version.ScrapedAt
+			break
+		}
+	}
+
+	last, _ := time.Parse("2006-01-02", report.LastLogged)
+	versions, err = scraper.LoadDiaryVersions(user, last)
+	if err != nil {
+		return err
+	}
+	if len(versions) > 0 {
+		report.LastLoggedAt = versions[len(versions)-1].ScrapedAt
+	}
+
+	return nil
+}
+
+func streakEndingAt(logged map[time.Time]scraper.DiaryEntry, end, from time.Time) int {
+	streak := 0
+	for date := end; !date.Before(from); date = date.AddDate(0, 0, -1) {
+		if _, ok := logged[date]; !ok {
+			break
+		}
+		streak++
+	}
+	return streak
+}
@@ -0,0 +1,81 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+func writeVersion(t *testing.T, user scraper.User, day string, number int, scrapedAt string, entry scraper.DiaryEntry) {
+	t.Helper()
+
+	dir := filepath.Join(scraper.OutputDir, scraper.HistoryDir, user.Username+"_"+day)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(scraper.DiaryVersion{Version: number, ScrapedAt: scrapedAt, Entry: entry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("v%04d.json", number)), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddLoggedTimes(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	user := scraper.User{Username: "ana", ID: "1"}
+	logged := func(date string) scraper.DiaryEntry {
+		return scraper.DiaryEntry{Date: date, Calories: "500", Meals: []scraper.MealData{
+			{Name: "Café da Manhã", Items: []scraper.FoodItem{{Name: "Pão", Calories: "500"}}},
+		}}
+	}
+
+	writeVersion(t, user, "2025-05-12", 1, "2025-05-12T06:00:00Z", scraper.DiaryEntry{Date: "12/05/2025"})
+	writeVersion(t, user, "2025-05-12", 2, "2025-05-12T09:00:00Z", logged("12/05/2025"))
+	writeVersion(t, user, "2025-05-13", 1, "2025-05-13T09:00:00Z", logged("13/05/2025"))
+	writeVersion(t, user, "2025-05-13", 2, "2025-05-13T21:00:00Z", logged("13/05/2025"))
+
+	from := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 13, 0, 0, 0, 0, time.UTC)
+	report := ComputeConsistency(user.Username, from, to, []scraper.DiaryEntry{logged("12/05/2025"), logged("13/05/2025")})
+
+	if err := AddLoggedTimes(&report, user); err != nil {
+		t.Fatal(err)
+	}
+	if report.FirstLoggedAt != "2025-05-12T09:00:00Z" {
+		t.Errorf("FirstLoggedAt = %q, want the first version with food", report.FirstLoggedAt)
+	}
+	if report.LastLoggedAt != "2025-05-13T21:00:00Z" {
+		t.Errorf("LastLoggedAt = %q, want the latest version of the last day", report.LastLoggedAt)
+	}
+}
+
+func TestConsistencyMealCoverage(t *testing.T) {
+	snacks := scraper.DiaryEntry{Date: "12/05/2025", Calories: "300", Meals: []scraper.MealData{
+		{Name: "Lanches/Outros", Items: []scraper.FoodItem{{Name: "Fruta", Calories: "100"}}},
+		{Name: "Lanche", Items: []scraper.FoodItem{{Name: "Iogurte", Calories: "200"}}},
+	}}
+
+	day := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	report := ComputeConsistency("ana", day, day, []scraper.DiaryEntry{snacks})
+
+	for _, coverage := range report.MealCoverage {
+		if coverage.Meal == scraper.SlotSnacks && (coverage.DaysLogged != 1 || coverage.Coverage != 1) {
+			t.Errorf("snacks coverage = %+v, want one day and 100%%", coverage)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package analytics
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+type FoodStats struct {
+	Key      string  `json:"key"`
+	Name     string  `json:"name"`
+	Count    int     `json:"count"`
+	Days     int     `json:"days"`
+	Users    int     `json:"users"`
+	Calories float64 `json:"calories"`
+	Fat      float64 `json:"fat"`
+	Carbs    float64 `json:"carbs"`
+	Protein  float64 `json:"protein"`
+}
+
+type FoodRanking struct {
+	From          string      `json:"from"`
+	To            string      `json:"to"`
+	Users         []string    `json:"users"`
+	DistinctFoods int         `json:"distinct_foods"`
+	MostFrequent  []FoodStats `json:"most_frequent"`
+	TopCalories   []FoodStats `json:"top_calories"`
+	TopProtein    []FoodStats `json:"top_protein"`
+}
+
+type FoodShift struct {
+	Key            string  `json:"key"`
+	Name           string  `json:"name"`
+	CountBefore    int     `json:"count_before"`
+	CountAfter     int     `json:"count_after"`
+	CountChange    int     `json:"count_change"`
+	CaloriesBefore float64 `json:"calories_before"`
+	CaloriesAfter  float64 `json:"calories_after"`
+	CaloriesChange float64 `json:"calories_change"`
+	RankBefore     int     `json:"rank_before,omitempty"`
+	RankAfter      int     `json:"rank_after,omitempty"`
+}
+
+type FoodReport struct {
+	Current  FoodRanking  `json:"current"`
+	Previous *FoodRanking `json:"previous,omitempty"`
+	Shifts   []FoodShift  `json:"shifts,omitempty"`
+}
+
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// NormalizeFoodName reduces a food name to a grouping key: lower case,
+// without accents, parenthesized details or punctuation, so "Arroz Branco",
+// "arroz branco" and "Arroz Branco (Cozido)" all count as the same food.
+func NormalizeFoodName(name string) string {
+	name = accentReplacer.Replace(strings.ToLower(name))
+
+	var b strings.Builder
+	depth := 0
+	for _, r := range name {
+		switch {
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			depth = max(0, depth-1)
+		case depth > 0:
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+type foodTally struct {
+	FoodStats
+	names map[string]int
+	days  map[string]bool
+	users map[string]bool
+}
+
+// tallyFoods sums every logged item by normalized name. The display name is
+// the spelling used most often.
+func tallyFoods(entries map[string][]scraper.DiaryEntry) map[string]FoodStats {
+	tallies := make(map[string]*foodTally)
+
+	for username, userEntries := range entries {
+		for _, entry := range userEntries {
+			for _, meal := range entry.Meals {
+				for _, item := range meal.Items {
+					key := NormalizeFoodName(item.Name)
+					if key == "" {
+						continue
+					}
+
+					tally, ok := tallies[key]
+					if !ok {
+						tally = &foodTally{
+							FoodStats: FoodStats{Key: key},
+							names:     make(map[string]int),
+							days:      make(map[string]bool),
+							users:     make(map[string]bool),
+						}
+						tallies[key] = tally
+					}
+
+					tally.Count++
+					tally.Calories += scraper.ParseNumber(item.Calories)
+					tally.Fat += scraper.ParseNumber(item.Fat)
+					tally.Carbs += scraper.ParseNumber(item.Carbs)
+					tally.Protein += scraper.ParseNumber(item.Protein)
+					tally.names[strings.TrimSpace(item.Name)]++
+					tally.days[username+"/"+entry.Date] = true
+					tally.users[username] = true
+				}
+			}
+		}
+	}
+
+	foods := make(map[string]FoodStats, len(tallies))
+	for key, tally := range tallies {
+		stats := tally.FoodStats
+		stats.Days = len(tally.days)
+		stats.Users = len(tally.users)
+
+		best := 0
+		for name, count := range tally.names {
+			if count > best || (count == best && name < stats.Name) {
+				stats.Name = name
+				best = count
+			}
+		}
+
+		foods[key] = stats
+	}
+
+	return foods
+}
+
+// topFoods sorts by value, highest first, breaking ties by key so rankings
+// are stable. A limit of 0 keeps every food.
+func topFoods(foods map[string]FoodStats, limit int, value func(FoodStats) float64) []FoodStats {
+	sorted := []FoodStats{}
+	for _, food := range foods {
+		if value(food) > 0 {
+			sorted = append(sorted, food)
+		}
+	}
+
+	slices.SortFunc(sorted, func(a, b FoodStats) int {
+		if c := cmp.Compare(value(b), value(a)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	return sorted
+}
+
+func byCount(food FoodStats) float64 { return float64(food.Count) }
+
+func rankFoods(from, to time.Time, entries map[string][]scraper.DiaryEntry, foods map[string]FoodStats, limit int) FoodRanking {
+	users := []string{}
+	for username := range entries {
+		users = append(users, username)
+	}
+	slices.Sort(users)
+
+	return FoodRanking{
+		From:          from.Format("2006-01-02"),
+		To:            to.Format("2006-01-02"),
+		Users:         users,
+		DistinctFoods: len(foods),
+		MostFrequent:  topFoods(foods, limit, byCount),
+		TopCalories:   topFoods(foods, limit, func(food FoodStats) float64 { return food.Calories }),
+		TopProtein:    topFoods(foods, limit, func(food FoodStats) float64 { return food.Protein }),
+	}
+}
+
+// compareFoods lists how often the foods ranked in either period were eaten
+// in each, biggest changes first.
+func compareFoods(before, after map[string]FoodStats, limit int) []FoodShift {
+	ranks := func(foods map[string]FoodStats) map[string]int {
+		rank := make(map[string]int)
+		for i, food := range topFoods(foods, 0, byCount) {
+			rank[food.Key] = i + 1
+		}
+		return rank
+	}
+	beforeRank, afterRank := ranks(before), ranks(after)
+
+	shifts := []FoodShift{}
+	seen := make(map[string]bool)
+	for _, foods := range []map[string]FoodStats{after, before} {
+		for _, food := range topFoods(foods, limit, byCount) {
+			if seen[food.Key] {
+				continue
+			}
+			seen[food.Key] = true
+
+			shift := FoodShift{
+				Key:            food.Key,
+				Name:           food.Name,
+				CountBefore:    before[food.Key].Count,
+				CountAfter:     after[food.Key].Count,
+				CaloriesBefore: before[food.Key].Calories,
+				CaloriesAfter:  after[food.Key].Calories,
+				RankBefore:     beforeRank[food.Key],
+				RankAfter:      afterRank[food.Key],
+			}
+			shift.CountChange = shift.CountAfter - shift.CountBefore
+			shift.CaloriesChange = shift.CaloriesAfter - shift.CaloriesBefore
+			shifts = append(shifts, shift)
+		}
+	}
+
+	slices.SortStableFunc(shifts, func(a, b FoodShift) int {
+		return cmp.Compare(abs(b.CountChange), abs(a.CountChange))
+	})
+
+	return shifts
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// ComputeFoodReport ranks the foods of the current period and, when previous
+// entries are given, how they shifted from the previous period.
+func ComputeFoodReport(from, to time.Time, current map[string][]scraper.DiaryEntry, previousFrom, previousTo time.Time, previous map[string][]scraper.DiaryEntry, limit int) FoodReport {
+	currentFoods := tallyFoods(current)
+	report := FoodReport{Current: rankFoods(from, to, current, currentFoods, limit)}
+
+	if previous == nil {
+		return report
+	}
+
+	previousFoods := tallyFoods(previous)
+	ranking := rankFoods(previousFrom, previousTo, previous, previousFoods, limit)
+	report.Previous = &ranking
+	report.Shifts = compareFoods(previousFoods, currentFoods, limit)
+
+	return report
+}
@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+func TestNormalizeFoodName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Arroz Branco", "arroz branco"},
+		{"arroz branco", "arroz branco"},
+		{"Arroz Branco (Cozido)", "arroz branco"},
+		{"  FEIJÃO-preto ", "feijao preto"},
+		{"Pão [integral] c/ manteiga", "pao c manteiga"},
+		{"Maçã (com casca (média))", "maca"},
+		{"Iogurte 0% gordura", "iogurte 0 gordura"},
+		{"(sem nome)", ""},
+	}
+
+	for _, test := range tests {
+		if got := NormalizeFoodName(test.name); got != test.want {
+			t.Errorf("NormalizeFoodName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestComputeFoodReport(t *testing.T) {
+	day := func(date string, items ...scraper.FoodItem) scraper.DiaryEntry {
+		return scraper.DiaryEntry{Date: date, Meals: []scraper.MealData{{Name: "Almoço", Items: items}}}
+	}
+	arroz := func(name string) scraper.FoodItem { return scraper.FoodItem{Name: name, Calories: "200", Protein: "4"} }
+	feijao := scraper.FoodItem{Name: "Feijão", Calories: "100", Protein: "7"}
+	ovo := scraper.FoodItem{Name: "Ovo", Calories: "80", Protein: "6"}
+
+	previous := map[string][]scraper.DiaryEntry{
+		"ana": {
+			day("05/05/2025", arroz("Arroz"), feijao),
+			day("06/05/2025", arroz("arroz"), feijao),
+			day("07/05/2025", feijao),
+		},
+	}
+	current := map[string][]scraper.DiaryEntry{
+		"ana": {day("12/05/2025", arroz("Arroz (cozido)"), ovo)},
+		"bia": {day("12/05/2025", ovo, ovo), day("13/05/2025", ovo)},
+	}
+
+	from := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 18, 0, 0, 0, 0, time.UTC)
+	previousFrom, previousTo := from.AddDate(0, 0, -7), to.AddDate(0, 0, -7)
+	report := ComputeFoodReport(from, to, current, previousFrom, previousTo, previous, 10)
+
+	ranking := report.Current
+	if !slices.Equal(ranking.Users, []string{"ana", "bia"}) || ranking.DistinctFoods != 2 {
+		t.Errorf("current ranking covers %v with %d foods, want ana and bia with 2", ranking.Users, ranking.DistinctFoods)
+	}
+	if len(ranking.MostFrequent) != 2 {
+		t.Fatalf("most frequent = %+v, want ovo and arroz", ranking.MostFrequent)
+	}
+	want := FoodStats{Key: "ovo", Name: "Ovo", Count: 4, Days: 3, Users: 2, Calories: 320, Protein: 24}
+	if ranking.MostFrequent[0] != want {
+		t.Errorf("most frequent = %+v, want %+v", ranking.MostFrequent[0], want)
+	}
+	if top := ranking.TopCalories[0]; top.Key != "ovo" || ranking.TopCalories[1].Key != "arroz" {
+		t.Errorf("top calories = %+v, want ovo before arroz", ranking.TopCalories)
+	}
+
+	if report.Previous == nil || report.Previous.From != "2025-05-05" || report.Previous.To != "2025-05-11" {
+		t.Fatalf("previous ranking = %+v, want 2025-05-05 to 2025-05-11", report.Previous)
+	}
+	if top := report.Previous.MostFrequent[0]; top.Key != "feijao" || top.Count != 3 {
+		t.Errorf("previous most frequent = %+v, want feijão 3 times", top)
+	}
+
+	shifts := []FoodShift{
+		{Key: "ovo", Name: "Ovo", CountAfter: 4, CountChange: 4, CaloriesAfter: 320, CaloriesChange: 320, RankAfter: 1},
+		{Key: "feijao", Name: "Feijão", CountBefore: 3, CountChange: -3, CaloriesBefore: 300, CaloriesChange: -300, RankBefore: 1},
+		{Key: "arroz", Name: "Arroz (cozido)", CountBefore: 2, CountAfter: 1, CountChange: -1, CaloriesBefore: 400, CaloriesAfter: 200, CaloriesChange: -200, RankBefore: 2, RankAfter: 2},
+	}
+	if !slices.Equal(report.Shifts, shifts) {
+		t.Errorf("shifts = %+v, want %+v", report.Shifts, shifts)
+	}
+
+	limited := ComputeFoodReport(from, to, current, previousFrom, previousTo, previous, 1)
+	if len(limited.Shifts) != 2 || limited.Shifts[0].Key != "ovo" || limited.Shifts[1].Key != "feijao" {
+		t.Errorf("shifts with limit 1 = %+v, want the top food of each period", limited.Shifts)
+	}
+
+	alone := ComputeFoodReport(from, to, current, previousFrom, previousTo, nil, 10)
+	if alone.Previous != nil || alone.Shifts != nil {
+		t.Errorf("report without previous entries = %+v, want no comparison", alone)
+	}
+}
@@ -0,0 +1,164 @@
+package analytics
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+type LeaderboardMetric string
+
+const (
+	MetricAdherenceScore   LeaderboardMetric = "adherence_score"
+	MetricCalorieAdherence LeaderboardMetric = "calorie_adherence"
+	MetricProteinAdherence LeaderboardMetric = "protein_adherence"
+	MetricProteinPerDay    LeaderboardMetric = "protein_per_day"
+	MetricLoggingRate      LeaderboardMetric = "logging_rate"
+	MetricLoggingStreak    LeaderboardMetric = "logging_streak"
+)
+
+// metricValue computes a user's score for a metric; false leaves the user
+// unranked, e.g. when they have no target for an adherence metric.
+type metricValue func(user scraper.User, from, to time.Time, entries []scraper.DiaryEntry) (float64, bool)
+
+// withinRate is the share of logged days whose deviation for a target was
+// within tolerance, in percent.
+func withinRate(report AdherenceReport, pick func(DayAdherence) *TargetDeviation) (float64, bool) {
+	days, within := 0, 0
+	for _, day := range report.Days {
+		if d := pick(day); d != nil {
+			days++
+			if d.Within {
+				within++
+			}
+		}
+	}
+
+	if days == 0 {
+		return 0, false
+	}
+	return float64(within) / float64(days) * 100, true
+}
+
+var leaderboardMetrics = map[LeaderboardMetric]metricValue{
+	MetricAdherenceScore: func(user scraper.User, from, to time.Time, entries []scraper.DiaryEntry) (float64, bool) {
+		report := ComputeAdherence(user, PeriodDay, from, to, entries)
+		return report.Overall.Score, report.TargetSource != "none" && report.Overall.DaysLogged > 0
+	},
+	MetricCalorieAdherence: func(user scraper.User, from, to time.Time, entries []scraper.DiaryEntry) (float64, bool) {
+		report := ComputeAdherence(user, PeriodDay, from, to, entries)
+		return withinRate(report, func(day DayAdherence) *TargetDeviation { return day.Calories })
+	},
+	MetricProteinAdherence: func(user scraper.User, from, to time.Time, entries []scraper.DiaryEntry) (float64, bool) {
+		report := ComputeAdherence(user, PeriodDay, from, to, entries)
+		return withinRate(report, func(day DayAdherence) *TargetDeviation { return day.Protein })
+	},
+	MetricProteinPerDay: func(user scraper.User, from, to time.Time, entries []scraper.DiaryEntry) (float64, bool) {
+		stats := periodStats(from, to, LoggedEntries(entries))
+		return stats.Protein.Average, stats.DaysLogged > 0
+	},
+	MetricLoggingRate: func(user scraper.User, from, to time.Time, entries []scraper.DiaryEntry) (float64, bool) {
+		return ComputeConsistency(user.Username, from, to, entries).LoggingRate * 100, true
+	},
+	MetricLoggingStreak: func(user scraper.User, from, to time.Time, entries []scraper.DiaryEntry) (float64, bool) {
+		return float64(ComputeConsistency(user.Username, from, to, entries).CurrentStreak), true
+	},
+}
+
+func ParseLeaderboardMetric(value string) (LeaderboardMetric, error) {
+	if value == "" {
+		return MetricAdherenceScore, nil
+	}
+	if _, ok := leaderboardMetrics[LeaderboardMetric(value)]; ok {
+		return LeaderboardMetric(value), nil
+	}
+
+	names := []string{}
+	for metric := range leaderboardMetrics {
+		names = append(names, string(metric))
+	}
+	slices.Sort(names)
+
+	return "", fmt.Errorf("unknown metric %q, use one of %s", value, strings.Join(names, ", "))
+}
+
+// CurrentPeriod returns the period containing date, cut off at date so the
+// days still ahead don't count against anyone.
+func CurrentPeriod(period Period, date time.Time) (time.Time, time.Time) {
+	start := PeriodStart(period, date)
+	end := PeriodEnd(period, start)
+	if end.After(date) {
+		end = date
+	}
+	return start, end
+}
+
+type LeaderboardEntry struct {
+	Rank       int     `json:"rank"`
+	Username   string  `json:"username"`
+	Value      float64 `json:"value"`
+	DaysLogged int     `json:"days_logged"`
+}
+
+type Leaderboard struct {
+	Metric   LeaderboardMetric  `json:"metric"`
+	Period   Period             `json:"period"`
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	Entries  []LeaderboardEntry `json:"entries"`
+	Unranked int                `json:"unranked"`
+}
+
+// ComputeLeaderboard ranks users on metric, highest first, with tied users
+// sharing a rank. Users who opted out are left out entirely and anonymous
+// users are listed as "anonymous". entries are keyed by username.
+func ComputeLeaderboard(metric LeaderboardMetric, period Period, from, to time.Time, users []scraper.User, entries map[string][]scraper.DiaryEntry) Leaderboard {
+	leaderboard := Leaderboard{
+		Metric:  metric,
+		Period:  period,
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		Entries: []LeaderboardEntry{},
+	}
+
+	value := leaderboardMetrics[metric]
+	for _, user := range users {
+		if user.Privacy != nil && user.Privacy.LeaderboardOptOut {
+			continue
+		}
+
+		score, ok := value(user, from, to, entries[user.Username])
+		if !ok {
+			leaderboard.Unranked++
+			continue
+		}
+
+		entry := LeaderboardEntry{
+			Username:   user.Username,
+			Value:      score,
+			DaysLogged: len(LoggedEntries(entries[user.Username])),
+		}
+		if user.Privacy != nil && user.Privacy.Anonymous {
+			entry.Username = "anonymous"
+		}
+		leaderboard.Entries = append(leaderboard.Entries, entry)
+	}
+
+	slices.SortStableFunc(leaderboard.Entries, func(a, b LeaderboardEntry) int {
+		return cmp.Compare(b.Value, a.Value)
+	})
+
+	for i := range leaderboard.Entries {
+		if i > 0 && leaderboard.Entries[i].Value == leaderboard.Entries[i-1].Value {
+			leaderboard.Entries[i].Rank = leaderboard.Entries[i-1].Rank
+		} else {
+			leaderboard.Entries[i].Rank = i + 1
+		}
+	}
+
+	return leaderboard
+}
@@ -0,0 +1,109 @@
+package analytics
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+func loggedDay(date, calories, protein string) scraper.DiaryEntry {
+	return scraper.DiaryEntry{Date: date, Calories: calories, Protein: protein, Meals: []scraper.MealData{
+		{Name: "Almoço", Items: []scraper.FoodItem{{Name: "Frango", Calories: calories, Protein: protein}}},
+	}}
+}
+
+func TestComputeLeaderboardRanksAcrossUsers(t *testing.T) {
+	users := []scraper.User{
+		{Username: "ana", ID: "1"},
+		{Username: "bia", ID: "2"},
+		{Username: "caio", ID: "3"},
+		{Username: "dani", ID: "4", Privacy: &scraper.Privacy{LeaderboardOptOut: true}},
+		{Username: "eva", ID: "5", Privacy: &scraper.Privacy{Anonymous: true}},
+		{Username: "fabi", ID: "6"},
+	}
+	entries := map[string][]scraper.DiaryEntry{
+		"ana":  {loggedDay("12/05/2025", "2000", "90"), loggedDay("13/05/2025", "2000", "110")},
+		"bia":  {loggedDay("12/05/2025", "1800", "120")},
+		"caio": {loggedDay("13/05/2025", "2200", "100"), {Date: "14/05/2025"}},
+		"dani": {loggedDay("12/05/2025", "2500", "200")},
+		"eva":  {loggedDay("12/05/2025", "1500", "70"), loggedDay("14/05/2025", "1600", "90")},
+		"fabi": {{Date: "12/05/2025"}},
+	}
+
+	from := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 18, 0, 0, 0, 0, time.UTC)
+	leaderboard := ComputeLeaderboard(MetricProteinPerDay, PeriodWeek, from, to, users, entries)
+
+	want := []LeaderboardEntry{
+		{Rank: 1, Username: "bia", Value: 120, DaysLogged: 1},
+		{Rank: 2, Username: "ana", Value: 100, DaysLogged: 2},
+		{Rank: 2, Username: "caio", Value: 100, DaysLogged: 1},
+		{Rank: 4, Username: "anonymous", Value: 80, DaysLogged: 2},
+	}
+	if !slices.Equal(leaderboard.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", leaderboard.Entries, want)
+	}
+	if leaderboard.Unranked != 1 {
+		t.Errorf("unranked = %d, want 1 for fabi, who logged nothing", leaderboard.Unranked)
+	}
+	if leaderboard.From != "2025-05-12" || leaderboard.To != "2025-05-18" {
+		t.Errorf("range = %s to %s", leaderboard.From, leaderboard.To)
+	}
+}
+
+func TestComputeLeaderboardCalorieAdherence(t *testing.T) {
+	tolerance := 10.0
+	users := []scraper.User{
+		{Username: "ana", ID: "1", Targets: &scraper.Targets{Calories: 2000, Tolerance: &tolerance}},
+		{Username: "bia", ID: "2"},
+		{Username: "caio", ID: "3"},
+	}
+
+	withGoal := func(entry scraper.DiaryEntry) scraper.DiaryEntry {
+		entry.Summary = &scraper.DailySummary{CalorieGoal: "2.000"}
+		return entry
+	}
+	entries := map[string][]scraper.DiaryEntry{
+		"ana": {
+			loggedDay("12/05/2025", "2000", "100"),
+			loggedDay("13/05/2025", "2150", "100"),
+			loggedDay("14/05/2025", "2500", "100"),
+			loggedDay("15/05/2025", "1900", "100"),
+		},
+		"bia": {loggedDay("12/05/2025", "2000", "100")},
+		"caio": {
+			withGoal(loggedDay("12/05/2025", "2000", "100")),
+			withGoal(loggedDay("13/05/2025", "1000", "100")),
+		},
+	}
+
+	from := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 18, 0, 0, 0, 0, time.UTC)
+	leaderboard := ComputeLeaderboard(MetricCalorieAdherence, PeriodWeek, from, to, users, entries)
+
+	want := []LeaderboardEntry{
+		{Rank: 1, Username: "ana", Value: 75, DaysLogged: 4},
+		{Rank: 2, Username: "caio", Value: 50, DaysLogged: 2},
+	}
+	if !slices.Equal(leaderboard.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", leaderboard.Entries, want)
+	}
+	if leaderboard.Unranked != 1 {
+		t.Errorf("unranked = %d, want 1 for bia, who has no calorie target", leaderboard.Unranked)
+	}
+}
+
+func TestCurrentPeriodStopsAtDate(t *testing.T) {
+	date := time.Date(2025, time.May, 14, 0, 0, 0, 0, time.UTC)
+
+	from, to := CurrentPeriod(PeriodWeek, date)
+	if from.Format("02/01/2006") != "12/05/2025" || !to.Equal(date) {
+		t.Errorf("current week = %s to %s, want 12/05/2025 to 14/05/2025", from.Format("02/01/2006"), to.Format("02/01/2006"))
+	}
+
+	if _, err := ParseLeaderboardMetric("calories"); err == nil {
+		t.Error("ParseLeaderboardMetric accepted an unknown metric")
+	}
+}
@@ -0,0 +1,134 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+type SlotShare struct {
+	Slot            scraper.MealSlot `json:"slot"`
+	DaysLogged      int              `json:"days_logged"`
+	Calories        float64          `json:"calories"`
+	Fat             float64          `json:"fat"`
+	Carbs           float64          `json:"carbs"`
+	Protein         float64          `json:"protein"`
+	CaloriesPct     float64          `json:"calories_pct"`
+	FatPct          float64          `json:"fat_pct"`
+	CarbsPct        float64          `json:"carbs_pct"`
+	ProteinPct      float64          `json:"protein_pct"`
+	AverageCalories float64          `json:"average_calories"`
+}
+
+type PeriodMealDistribution struct {
+	Start      string      `json:"start"`
+	End        string      `json:"end"`
+	DaysLogged int         `json:"days_logged"`
+	Slots      []SlotShare `json:"slots"`
+}
+
+type MealDistribution struct {
+	Username string                   `json:"username"`
+	Period   Period                   `json:"period"`
+	From     string                   `json:"from"`
+	To       string                   `json:"to"`
+	Overall  PeriodMealDistribution   `json:"overall"`
+	Periods  []PeriodMealDistribution `json:"periods"`
+}
+
+func percent(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return part / total * 100
+}
+
+func periodMealDistribution(start, end time.Time, entries []scraper.DiaryEntry) PeriodMealDistribution {
+	shares := make(map[scraper.MealSlot]*SlotShare)
+	days := make(map[scraper.MealSlot]map[string]bool)
+	var total SlotShare
+
+	for _, entry := range entries {
+		for _, meal := range entry.Meals {
+			if len(meal.Items) == 0 {
+				continue
+			}
+
+			slot := scraper.CanonicalMealSlot(meal.Name)
+			share, ok := shares[slot]
+			if !ok {
+				share = &SlotShare{Slot: slot}
+				shares[slot] = share
+				days[slot] = make(map[string]bool)
+			}
+
+			// Several meals can fall into one slot on the same day, e.g.
+			// two snack sections.
+			if !days[slot][entry.Date] {
+				days[slot][entry.Date] = true
+				share.DaysLogged++
+			}
+			share.Calories += scraper.ParseNumber(meal.Calories)
+			share.Fat += scraper.ParseNumber(meal.Fat)
+			share.Carbs += scraper.ParseNumber(meal.Carbs)
+			share.Protein += scraper.ParseNumber(meal.Protein)
+
+			total.Calories += scraper.ParseNumber(meal.Calories)
+			total.Fat += scraper.ParseNumber(meal.Fat)
+			total.Carbs += scraper.ParseNumber(meal.Carbs)
+			total.Protein += scraper.ParseNumber(meal.Protein)
+		}
+	}
+
+	distribution := PeriodMealDistribution{
+		Start:      start.Format("2006-01-02"),
+		End:        end.Format("2006-01-02"),
+		DaysLogged: len(entries),
+		Slots:      []SlotShare{},
+	}
+
+	for _, slot := range scraper.MealSlots {
+		share, ok := shares[slot]
+		if !ok {
+			if slot == scraper.SlotOther {
+				continue
+			}
+			share = &SlotShare{Slot: slot}
+		}
+
+		share.CaloriesPct = percent(share.Calories, total.Calories)
+		share.FatPct = percent(share.Fat, total.Fat)
+		share.CarbsPct = percent(share.Carbs, total.Carbs)
+		share.ProteinPct = percent(share.Protein, total.Protein)
+		if share.DaysLogged > 0 {
+			share.AverageCalories = share.Calories / float64(share.DaysLogged)
+		}
+
+		distribution.Slots = append(distribution.Slots, *share)
+	}
+
+	return distribution
+}
+
+// ComputeMealDistribution splits calories and macros across meal slots per
+// period. FatSecret doesn't record when a meal was eaten, so the slot is as
+// close to meal timing as the diary gets. Average calories are per day the
+// slot was logged.
+func ComputeMealDistribution(username string, period Period, from, to time.Time, entries []scraper.DiaryEntry) MealDistribution {
+	distribution := MealDistribution{
+		Username: username,
+		Period:   period,
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Periods:  []PeriodMealDistribution{},
+	}
+
+	starts, groups := GroupByPeriod(period, entries)
+	for _, start := range starts {
+		distribution.Periods = append(distribution.Periods, periodMealDistribution(start, PeriodEnd(period, start), groups[start]))
+	}
+
+	distribution.Overall = periodMealDistribution(from, to, LoggedEntries(entries))
+
+	return distribution
+}
@@ -0,0 +1,36 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+func TestMealDistributionCountsDaysPerSlot(t *testing.T) {
+	snack := func(name, calories string) scraper.MealData {
+		return scraper.MealData{Name: name, Calories: calories, Items: []scraper.FoodItem{{Name: "Fruta", Calories: calories}}}
+	}
+	entries := []scraper.DiaryEntry{
+		{Date: "12/05/2025", Calories: "300", Meals: []scraper.MealData{snack("Lanches/Outros", "100"), snack("Lanche", "200")}},
+		{Date: "13/05/2025", Calories: "100", Meals: []scraper.MealData{snack("Lanches/Outros", "100")}},
+	}
+
+	from := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 13, 0, 0, 0, 0, time.UTC)
+	distribution := ComputeMealDistribution("ana", PeriodWeek, from, to, entries)
+
+	for _, share := range distribution.Overall.Slots {
+		if share.Slot != scraper.SlotSnacks {
+			continue
+		}
+		if share.DaysLogged != 2 {
+			t.Errorf("snacks logged on %d days, want 2", share.DaysLogged)
+		}
+		if share.AverageCalories != 200 {
+			t.Errorf("snacks average %v kcal per day, want 200", share.AverageCalories)
+		}
+		return
+	}
+	t.Fatal("no snacks slot in the distribution")
+}
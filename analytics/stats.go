@@ -0,0 +1,195 @@
+package analytics
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+type Period string
+
+const (
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+)
+
+func ParsePeriod(value string) (Period, error) {
+	switch Period(value) {
+	case "":
+		return PeriodWeek, nil
+	case PeriodDay, PeriodWeek, PeriodMonth:
+		return Period(value), nil
+	}
+	return "", fmt.Errorf("unknown period %q, use day, week or month", value)
+}
+
+type Metric struct {
+	Total   float64 `json:"total"`
+	Average float64 `json:"average"`
+	Median  float64 `json:"median"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+}
+
+type PeriodStats struct {
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	DaysLogged int    `json:"days_logged"`
+	Calories   Metric `json:"calories"`
+	Fat        Metric `json:"fat"`
+	Carbs      Metric `json:"carbs"`
+	Protein    Metric `json:"protein"`
+}
+
+type Stats struct {
+	Username string        `json:"username"`
+	Period   Period        `json:"period"`
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Overall  PeriodStats   `json:"overall"`
+	Periods  []PeriodStats `json:"periods"`
+}
+
+// EntryDate parses the diary date of an entry.
+func EntryDate(entry scraper.DiaryEntry) (time.Time, error) {
+	return time.Parse("02/01/2006", entry.Date)
+}
+
+// IsLogged reports whether anything was logged on the day. Days that were
+// scraped but left empty would otherwise drag every average down.
+func IsLogged(entry scraper.DiaryEntry) bool {
+	for _, meal := range entry.Meals {
+		if len(meal.Items) > 0 {
+			return true
+		}
+	}
+	return scraper.ParseNumber(entry.Calories) > 0
+}
+
+// LoggedEntries drops unlogged days and sorts the rest oldest first.
+func LoggedEntries(entries []scraper.DiaryEntry) []scraper.DiaryEntry {
+	logged := []scraper.DiaryEntry{}
+	for _, entry := range entries {
+		if _, err := EntryDate(entry); err == nil && IsLogged(entry) {
+			logged = append(logged, entry)
+		}
+	}
+
+	slices.SortFunc(logged, func(a, b scraper.DiaryEntry) int {
+		aDate, _ := EntryDate(a)
+		bDate, _ := EntryDate(b)
+		return aDate.Compare(bDate)
+	})
+
+	return logged
+}
+
+// PeriodStart returns the first day of the period containing date. Weeks
+// start on Monday.
+func PeriodStart(period Period, date time.Time) time.Time {
+	switch period {
+	case PeriodWeek:
+		offset := (int(date.Weekday()) + 6) % 7
+		return date.AddDate(0, 0, -offset)
+	case PeriodMonth:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	}
+	return date
+}
+
+// PeriodEnd returns the last day of the period starting at start.
+func PeriodEnd(period Period, start time.Time) time.Time {
+	switch period {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 6)
+	case PeriodMonth:
+		return start.AddDate(0, 1, -1)
+	}
+	return start
+}
+
+func metric(values []float64) Metric {
+	if len(values) == 0 {
+		return Metric{}
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	m := Metric{Min: sorted[0], Max: sorted[len(sorted)-1]}
+	for _, value := range sorted {
+		m.Total += value
+	}
+	m.Average = m.Total / float64(len(sorted))
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		m.Median = (sorted[middle-1] + sorted[middle]) / 2
+	} else {
+		m.Median = sorted[middle]
+	}
+
+	return m
+}
+
+func periodStats(start, end time.Time, entries []scraper.DiaryEntry) PeriodStats {
+	var calories, fat, carbs, protein []float64
+	for _, entry := range entries {
+		calories = append(calories, scraper.ParseNumber(entry.Calories))
+		fat = append(fat, scraper.ParseNumber(entry.Fat))
+		carbs = append(carbs, scraper.ParseNumber(entry.Carbs))
+		protein = append(protein, scraper.ParseNumber(entry.Protein))
+	}
+
+	return PeriodStats{
+		Start:      start.Format("2006-01-02"),
+		End:        end.Format("2006-01-02"),
+		DaysLogged: len(entries),
+		Calories:   metric(calories),
+		Fat:        metric(fat),
+		Carbs:      metric(carbs),
+		Protein:    metric(protein),
+	}
+}
+
+// GroupByPeriod splits logged entries into consecutive periods, keyed by the
+// period's first day, in chronological order.
+func GroupByPeriod(period Period, entries []scraper.DiaryEntry) ([]time.Time, map[time.Time][]scraper.DiaryEntry) {
+	starts := []time.Time{}
+	groups := make(map[time.Time][]scraper.DiaryEntry)
+
+	for _, entry := range LoggedEntries(entries) {
+		date, _ := EntryDate(entry)
+		start := PeriodStart(period, date)
+		if _, ok := groups[start]; !ok {
+			starts = append(starts, start)
+		}
+		groups[start] = append(groups[start], entry)
+	}
+
+	return starts, groups
+}
+
+// ComputeStats aggregates calories and macros per period over the logged days
+// between from and to.
+func ComputeStats(username string, period Period, from, to time.Time, entries []scraper.DiaryEntry) Stats {
+	stats := Stats{
+		Username: username,
+		Period:   period,
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Periods:  []PeriodStats{},
+	}
+
+	starts, groups := GroupByPeriod(period, entries)
+	for _, start := range starts {
+		stats.Periods = append(stats.Periods, periodStats(start, PeriodEnd(period, start), groups[start]))
+	}
+
+	stats.Overall = periodStats(from, to, LoggedEntries(entries))
+
+	return stats
+}
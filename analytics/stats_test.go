@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+func TestPeriodBounds(t *testing.T) {
+	date := time.Date(2025, time.May, 15, 0, 0, 0, 0, time.UTC) // a Thursday
+
+	tests := []struct {
+		period     Period
+		start, end string
+	}{
+		{PeriodDay, "2025-05-15", "2025-05-15"},
+		{PeriodWeek, "2025-05-12", "2025-05-18"},
+		{PeriodMonth, "2025-05-01", "2025-05-31"},
+	}
+
+	for _, test := range tests {
+		start := PeriodStart(test.period, date)
+		end := PeriodEnd(test.period, start)
+		if got := start.Format("2006-01-02"); got != test.start {
+			t.Errorf("PeriodStart(%s) = %s, want %s", test.period, got, test.start)
+		}
+		if got := end.Format("2006-01-02"); got != test.end {
+			t.Errorf("PeriodEnd(%s) = %s, want %s", test.period, got, test.end)
+		}
+	}
+
+	sunday := time.Date(2025, time.May, 18, 0, 0, 0, 0, time.UTC)
+	if got := PeriodStart(PeriodWeek, sunday).Format("2006-01-02"); got != "2025-05-12" {
+		t.Errorf("week of Sunday 18/05 starts %s, want Monday 12/05", got)
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	day := func(date, calories, protein string) scraper.DiaryEntry {
+		return scraper.DiaryEntry{Date: date, Calories: calories, Protein: protein, Meals: []scraper.MealData{
+			{Name: "Almoço", Items: []scraper.FoodItem{{Name: "Arroz", Calories: calories}}},
+		}}
+	}
+	entries := []scraper.DiaryEntry{
+		day("20/05/2025", "3.000", "150"),
+		day("12/05/2025", "1.000", "50"),
+		{Date: "16/05/2025", Calories: "0"},
+		day("14/05/2025", "2.000", "100"),
+		day("19/05/2025", "1.500", "75"),
+	}
+
+	from := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 25, 0, 0, 0, 0, time.UTC)
+	stats := ComputeStats("ana", PeriodWeek, from, to, entries)
+
+	overall := stats.Overall
+	if overall.DaysLogged != 4 {
+		t.Errorf("overall counts %d logged days, want 4 without the empty 16/05", overall.DaysLogged)
+	}
+	want := Metric{Total: 7500, Average: 1875, Median: 1750, Min: 1000, Max: 3000}
+	if overall.Calories != want {
+		t.Errorf("overall calories = %+v, want %+v", overall.Calories, want)
+	}
+	if overall.Protein.Average != 93.75 {
+		t.Errorf("overall protein average = %v, want 93.75", overall.Protein.Average)
+	}
+
+	if len(stats.Periods) != 2 {
+		t.Fatalf("got %d weeks, want 2", len(stats.Periods))
+	}
+	weeks := []struct {
+		start, end string
+		calories   Metric
+	}{
+		{"2025-05-12", "2025-05-18", Metric{Total: 3000, Average: 1500, Median: 1500, Min: 1000, Max: 2000}},
+		{"2025-05-19", "2025-05-25", Metric{Total: 4500, Average: 2250, Median: 2250, Min: 1500, Max: 3000}},
+	}
+	for i, week := range weeks {
+		got := stats.Periods[i]
+		if got.Start != week.start || got.End != week.end || got.DaysLogged != 2 {
+			t.Errorf("week %d = %s to %s with %d days, want %s to %s with 2", i, got.Start, got.End, got.DaysLogged, week.start, week.end)
+		}
+		if got.Calories != week.calories {
+			t.Errorf("week %d calories = %+v, want %+v", i, got.Calories, week.calories)
+		}
+	}
+}
+
+func TestMetricMedianOfOddCount(t *testing.T) {
+	if got := metric([]float64{9, 1, 4}); got.Median != 4 || got.Min != 1 || got.Max != 9 {
+		t.Errorf("metric = %+v, want median 4, min 1 and max 9", got)
+	}
+	if got := metric(nil); got != (Metric{}) {
+		t.Errorf("metric of no values = %+v, want zero", got)
+	}
+}
@@ -0,0 +1,193 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+const (
+	// kcalPerKg is the energy stored in a kilogram of body weight change.
+	kcalPerKg = 7700
+
+	// z95 is the two-sided z value for a 95% confidence interval.
+	z95 = 1.96
+
+	minTDEEWeighIns = 3
+	minTDEEDays     = 7
+)
+
+type TDEEEstimate struct {
+	Start               string  `json:"start"`
+	End                 string  `json:"end"`
+	DaysLogged          int     `json:"days_logged"`
+	WeighIns            int     `json:"weigh_ins"`
+	AverageIntake       float64 `json:"average_intake"`
+	WeightChangePerWeek float64 `json:"weight_change_per_week"`
+	TrendStartWeight    float64 `json:"trend_start_weight"`
+	TrendEndWeight      float64 `json:"trend_end_weight"`
+	TDEE                float64 `json:"tdee"`
+	Lower               float64 `json:"lower"`
+	Upper               float64 `json:"upper"`
+}
+
+type TDEEReport struct {
+	Username   string         `json:"username"`
+	From       string         `json:"from"`
+	To         string         `json:"to"`
+	WindowDays int            `json:"window_days"`
+	Confidence float64        `json:"confidence"`
+	Current    *TDEEEstimate  `json:"current,omitempty"`
+	Estimates  []TDEEEstimate `json:"estimates"`
+	Reason     string         `json:"reason,omitempty"`
+}
+
+type weighIn struct {
+	date   time.Time
+	weight float64
+}
+
+func parseWeighIns(weights []scraper.WeightEntry) []weighIn {
+	weighIns := []weighIn{}
+	for _, entry := range weights {
+		date, err := time.Parse("02/01/2006", entry.Date)
+		weight := scraper.ParseNumber(entry.Weight)
+		if err != nil || weight <= 0 {
+			continue
+		}
+		weighIns = append(weighIns, weighIn{date: date, weight: weight})
+	}
+	return weighIns
+}
+
+// weightTrend fits a least-squares line through the weigh-ins and returns
+// its slope in kg per day, the slope's standard error and the fitted weight
+// at start and end.
+func weightTrend(weighIns []weighIn, start, end time.Time) (float64, float64, float64, float64) {
+	n := float64(len(weighIns))
+	days := func(date time.Time) float64 { return date.Sub(start).Hours() / 24 }
+
+	meanX, meanY := 0.0, 0.0
+	for _, w := range weighIns {
+		meanX += days(w.date)
+		meanY += w.weight
+	}
+	meanX /= n
+	meanY /= n
+
+	sxx, sxy := 0.0, 0.0
+	for _, w := range weighIns {
+		sxx += (days(w.date) - meanX) * (days(w.date) - meanX)
+		sxy += (days(w.date) - meanX) * (w.weight - meanY)
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	residuals := 0.0
+	for _, w := range weighIns {
+		r := w.weight - (intercept + slope*days(w.date))
+		residuals += r * r
+	}
+	stderr := math.Sqrt(residuals / (n - 2) / sxx)
+
+	return slope, stderr, intercept, intercept + slope*days(end)
+}
+
+// estimateTDEE balances average intake against the weight trend between
+// start and end: maintenance is what was eaten minus what was stored. The
+// interval combines the uncertainty of both. It returns false when there
+// isn't enough data.
+func estimateTDEE(start, end time.Time, entries []scraper.DiaryEntry, weighIns []weighIn) (TDEEEstimate, bool) {
+	estimate := TDEEEstimate{Start: start.Format("2006-01-02"), End: end.Format("2006-01-02")}
+
+	intake := []float64{}
+	for _, entry := range entries {
+		date, _ := EntryDate(entry)
+		if !date.Before(start) && !date.After(end) {
+			intake = append(intake, scraper.ParseNumber(entry.Calories))
+		}
+	}
+
+	window := []weighIn{}
+	for _, w := range weighIns {
+		if !w.date.Before(start) && !w.date.After(end) {
+			window = append(window, w)
+		}
+	}
+
+	estimate.DaysLogged = len(intake)
+	estimate.WeighIns = len(window)
+	if len(intake) < minTDEEDays || len(window) < minTDEEWeighIns || !window[len(window)-1].date.After(window[0].date) {
+		return estimate, false
+	}
+
+	m := metric(intake)
+	variance := 0.0
+	for _, value := range intake {
+		variance += (value - m.Average) * (value - m.Average)
+	}
+	intakeErr := math.Sqrt(variance/float64(len(intake)-1)) / math.Sqrt(float64(len(intake)))
+
+	slope, slopeErr, startWeight, endWeight := weightTrend(window, start, end)
+	storedErr := slopeErr * kcalPerKg
+
+	estimate.AverageIntake = m.Average
+	estimate.WeightChangePerWeek = slope * 7
+	estimate.TrendStartWeight = startWeight
+	estimate.TrendEndWeight = endWeight
+	estimate.TDEE = m.Average - slope*kcalPerKg
+
+	margin := z95 * math.Sqrt(intakeErr*intakeErr+storedErr*storedErr)
+	estimate.Lower = estimate.TDEE - margin
+	estimate.Upper = estimate.TDEE + margin
+
+	return estimate, true
+}
+
+// ComputeTDEE estimates maintenance calories over rolling windows of
+// windowDays, one ending every week back from to, so the estimate adapts as
+// metabolism and logging change. Average intake only counts logged days;
+// days left unlogged are assumed to look like the logged ones.
+func ComputeTDEE(username string, from, to time.Time, windowDays int, entries []scraper.DiaryEntry, weights []scraper.WeightEntry) TDEEReport {
+	report := TDEEReport{
+		Username:   username,
+		From:       from.Format("2006-01-02"),
+		To:         to.Format("2006-01-02"),
+		WindowDays: windowDays,
+		Confidence: 0.95,
+		Estimates:  []TDEEEstimate{},
+	}
+
+	logged := LoggedEntries(entries)
+	weighIns := parseWeighIns(weights)
+
+	ends := []time.Time{}
+	for end := to; !end.AddDate(0, 0, -windowDays+1).Before(from); end = end.AddDate(0, 0, -7) {
+		ends = append([]time.Time{end}, ends...)
+	}
+	if len(ends) == 0 {
+		ends = append(ends, to)
+	}
+
+	for _, end := range ends {
+		start := end.AddDate(0, 0, -windowDays+1)
+		if start.Before(from) {
+			start = from
+		}
+
+		if estimate, ok := estimateTDEE(start, end, logged, weighIns); ok {
+			report.Estimates = append(report.Estimates, estimate)
+		}
+	}
+
+	if len(report.Estimates) == 0 {
+		report.Reason = "need at least 7 logged days and 3 weigh-ins within a window"
+		return report
+	}
+
+	current := report.Estimates[len(report.Estimates)-1]
+	report.Current = &current
+
+	return report
+}
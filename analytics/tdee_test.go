@@ -0,0 +1,100 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 0.01
+}
+
+// tdeeWeek is a week of intake averaging 2000 kcal, with a sample standard
+// deviation of sqrt(100000/6), and four weigh-ins on a line of -0.09 kg/day
+// with residuals of ±0.02 and ±0.06.
+func tdeeWeek() ([]scraper.DiaryEntry, []scraper.WeightEntry) {
+	entries := []scraper.DiaryEntry{}
+	for i, calories := range []string{"2000", "2100", "1900", "2000", "2200", "1800", "2000"} {
+		date := time.Date(2025, time.May, 12+i, 0, 0, 0, 0, time.UTC).Format("02/01/2006")
+		entries = append(entries, loggedDay(date, calories, "100"))
+	}
+
+	weights := []scraper.WeightEntry{
+		{Date: "12/05/2025", Weight: "80,0"},
+		{Date: "14/05/2025", Weight: "79,9"},
+		{Date: "16/05/2025", Weight: "79,6"},
+		{Date: "18/05/2025", Weight: "79,5"},
+	}
+
+	return entries, weights
+}
+
+func TestWeightTrend(t *testing.T) {
+	_, weights := tdeeWeek()
+	start := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.May, 18, 0, 0, 0, 0, time.UTC)
+
+	slope, stderr, startWeight, endWeight := weightTrend(parseWeighIns(weights), start, end)
+
+	// sxx = 20 and the squared residuals add up to 0.008, so the slope's
+	// standard error is sqrt(0.008 / 2 / 20).
+	if !near(slope, -0.09) || !near(startWeight, 80.02) || !near(endWeight, 79.48) {
+		t.Errorf("trend = %v kg/day from %v to %v, want -0.09 from 80.02 to 79.48", slope, startWeight, endWeight)
+	}
+	if math.Abs(stderr-math.Sqrt(0.0002)) > 1e-9 {
+		t.Errorf("slope standard error = %v, want %v", stderr, math.Sqrt(0.0002))
+	}
+}
+
+func TestComputeTDEE(t *testing.T) {
+	entries, weights := tdeeWeek()
+	from := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 18, 0, 0, 0, 0, time.UTC)
+
+	report := ComputeTDEE("ana", from, to, 7, entries, weights)
+	if report.Current == nil || len(report.Estimates) != 1 {
+		t.Fatalf("report = %+v, want one estimate", report)
+	}
+
+	estimate := *report.Current
+	if estimate.DaysLogged != 7 || estimate.WeighIns != 4 {
+		t.Errorf("estimate uses %d days and %d weigh-ins, want 7 and 4", estimate.DaysLogged, estimate.WeighIns)
+	}
+	if !near(estimate.AverageIntake, 2000) || !near(estimate.WeightChangePerWeek, -0.63) {
+		t.Errorf("intake %v kcal and %v kg/week, want 2000 and -0.63", estimate.AverageIntake, estimate.WeightChangePerWeek)
+	}
+
+	// 0.09 kg/day lost is 693 kcal/day drawn from storage on top of intake.
+	if !near(estimate.TDEE, 2693) {
+		t.Errorf("TDEE = %v, want 2693", estimate.TDEE)
+	}
+
+	// The margin is 1.96 * sqrt(intakeErr² + storedErr²) with intakeErr =
+	// sqrt(100000/6/7) and storedErr = sqrt(0.0002) * 7700.
+	margin := z95 * math.Sqrt(100000.0/6/7+0.0002*7700*7700)
+	if !near(estimate.Lower, 2693-margin) || !near(estimate.Upper, 2693+margin) {
+		t.Errorf("95%% interval = [%v, %v], want [%v, %v]", estimate.Lower, estimate.Upper, 2693-margin, 2693+margin)
+	}
+	if !near(estimate.Lower, 2459.12) || !near(estimate.Upper, 2926.88) {
+		t.Errorf("95%% interval = [%v, %v], want [2459.12, 2926.88]", estimate.Lower, estimate.Upper)
+	}
+}
+
+func TestComputeTDEEWindows(t *testing.T) {
+	entries, weights := tdeeWeek()
+	from := time.Date(2025, time.May, 5, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 18, 0, 0, 0, 0, time.UTC)
+
+	report := ComputeTDEE("ana", from, to, 7, entries, weights)
+	if len(report.Estimates) != 1 || report.Current.Start != "2025-05-12" || report.Current.End != "2025-05-18" {
+		t.Errorf("estimates = %+v, want only the week ending 18/05, the one with data", report.Estimates)
+	}
+
+	sparse := ComputeTDEE("ana", from, to, 7, entries, weights[:2])
+	if sparse.Current != nil || sparse.Reason == "" {
+		t.Errorf("report with two weigh-ins = %+v, want no estimate and a reason", sparse)
+	}
+}
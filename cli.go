@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/analytics"
+	"github.com/alissoncorsair/fatsecret-scrapper/export"
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+func runCommand(name string, args []string) error {
+	switch name {
+	case "export":
+		return exportCommand(args)
+	case "consistency":
+		return consistencyCommand(args)
+	}
+	return fmt.Errorf("unknown command %q", name)
+}
+
+func parseCommandDates(from, to string, defaultDays int) (time.Time, time.Time, error) {
+	toDate := time.Now().UTC().Truncate(24 * time.Hour)
+	fromDate := toDate.AddDate(0, 0, -defaultDays+1)
+
+	if from != "" {
+		date, err := time.Parse("02/01/2006", from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -from date, use DD/MM/YYYY")
+		}
+		fromDate = date
+	}
+
+	if to != "" {
+		date, err := time.Parse("02/01/2006", to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -to date, use DD/MM/YYYY")
+		}
+		toDate = date
+	}
+
+	return fromDate, toDate, nil
+}
+
+func commandUser(username string) (scraper.User, error) {
+	if username == "" {
+		return scraper.User{}, fmt.Errorf("-user is required")
+	}
+
+	user, found, err := scraper.FindUser(username)
+	if err != nil {
+		return scraper.User{}, err
+	}
+	if !found {
+		return scraper.User{}, fmt.Errorf("user %q not found", username)
+	}
+
+	return user, nil
+}
+
+func exportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "xlsx", "csv, xlsx or parquet")
+	username := flags.String("user", "", "username from users.json")
+	level := flags.String("level", "day", "csv level: day, meal or food")
+	from := flags.String("from", "", "first day, DD/MM/YYYY (default 30 days ago)")
+	to := flags.String("to", "", "last day, DD/MM/YYYY (default today)")
+	out := flags.String("out", "", "output file, or directory for parquet (default stdout)")
+	totals := flags.String("totals", "scraped", "scraped or computed from the item rows")
+	flags.Parse(args)
+
+	fromDate, toDate, err := parseCommandDates(*from, *to, 30)
+	if err != nil {
+		return err
+	}
+
+	mode, err := scraper.ParseTotalsMode(*totals)
+	if err != nil {
+		return err
+	}
+
+	user, err := commandUser(*username)
+	if err != nil {
+		return err
+	}
+
+	if *format == "parquet" {
+		if *out == "" {
+			return fmt.Errorf("-out directory is required for parquet")
+		}
+		return export.WriteParquet(func(path string) (io.WriteCloser, error) {
+			filename := filepath.Join(*out, filepath.FromSlash(path))
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				return nil, err
+			}
+			return os.Create(filename)
+		}, []scraper.User{user}, fromDate, toDate, mode)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %v", *out, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch *format {
+	case "xlsx":
+		return export.WriteXLSX(w, user, fromDate, toDate, mode)
+	case "csv":
+		exportLevel, err := export.ParseLevel(*level)
+		if err != nil {
+			return err
+		}
+		return export.WriteCSV(w, exportLevel, []scraper.User{user}, fromDate, toDate, mode)
+	}
+
+	return fmt.Errorf("unsupported format %q, use csv, xlsx or parquet", *format)
+}
+
+func consistencyCommand(args []string) error {
+	flags := flag.NewFlagSet("consistency", flag.ExitOnError)
+	username := flags.String("user", "", "username from users.json")
+	from := flags.String("from", "", "first day, DD/MM/YYYY (default 30 days ago)")
+	to := flags.String("to", "", "last day, DD/MM/YYYY (default today)")
+	flags.Parse(args)
+
+	fromDate, toDate, err := parseCommandDates(*from, *to, 30)
+	if err != nil {
+		return err
+	}
+
+	user, err := commandUser(*username)
+	if err != nil {
+		return err
+	}
+
+	entries, err := scraper.LoadDiaryEntries(user, fromDate, toDate)
+	if err != nil {
+		return err
+	}
+
+	report := analytics.ComputeConsistency(user.Username, fromDate, toDate, entries)
+	if err := analytics.AddLoggedTimes(&report, user); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n----- Logging consistency for %s (%s to %s) -----\n", report.Username, report.From, report.To)
+	fmt.Printf("Days logged: %d of %d (%.0f%%)\n", report.DaysLogged, report.TotalDays, report.LoggingRate*100)
+	fmt.Printf("Current streak: %d days\n", report.CurrentStreak)
+	fmt.Printf("Longest streak: %d days\n", report.LongestStreak)
+	fmt.Printf("Meals per day: %.1f\n", report.AverageMeals)
+	fmt.Printf("First logged: %s %s\n", report.FirstLogged, report.FirstLoggedAt)
+	fmt.Printf("Last logged: %s %s\n", report.LastLogged, report.LastLoggedAt)
+
+	fmt.Println("\nMeal coverage:")
+	for _, meal := range report.MealCoverage {
+		fmt.Printf("- %s: %d days, skipped %d (%.0f%%)\n", meal.Meal, meal.DaysLogged, meal.DaysSkipped, meal.Coverage*100)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package dashboard
+
+import (
+	"math"
+	"strconv"
+)
+
+// Charts are drawn as inline SVG by the templates; this file only computes
+// the geometry so the pages work without JavaScript or a CDN.
+
+const (
+	chartWidth   = 720
+	chartHeight  = 240
+	chartPadding = 40
+)
+
+type Series struct {
+	Name   string
+	Color  string
+	Values []float64
+}
+
+type Bar struct {
+	X, Y, Width, Height float64
+	Color               string
+	Title               string
+}
+
+type Tick struct {
+	Y     float64
+	Label string
+}
+
+type Label struct {
+	X    float64
+	Text string
+}
+
+type Chart struct {
+	Title   string
+	Width   float64
+	Height  float64
+	Left    float64
+	Bottom  float64
+	Bars    []Bar
+	Ticks   []Tick
+	Labels  []Label
+	Legend  []Series
+	TargetY float64
+	Target  string
+}
+
+// niceMax rounds max up to 1, 2 or 5 times a power of ten so the axis ticks
+// land on round numbers.
+func niceMax(max float64) float64 {
+	if max <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(max)))
+	for _, step := range []float64{1, 2, 5, 10} {
+		if step*magnitude >= max {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// barChart groups one bar per series for every label. A target above zero
+// is drawn as a horizontal line.
+func barChart(title string, labels []string, series []Series, target float64) Chart {
+	chart := Chart{
+		Title:  title,
+		Width:  chartWidth,
+		Height: chartHeight,
+		Left:   chartPadding,
+		Bottom: chartHeight - chartPadding/2,
+		Legend: series,
+	}
+
+	max := target
+	for _, s := range series {
+		for _, value := range s.Values {
+			max = math.Max(max, value)
+		}
+	}
+	max = niceMax(max)
+
+	plotHeight := chart.Bottom - chartPadding/2
+	y := func(value float64) float64 { return chart.Bottom - value/max*plotHeight }
+
+	for i := 0; i <= 4; i++ {
+		value := max * float64(i) / 4
+		chart.Ticks = append(chart.Ticks, Tick{Y: y(value), Label: strconv.FormatFloat(value, 'f', -1, 64)})
+	}
+
+	if len(labels) == 0 || len(series) == 0 {
+		return chart
+	}
+
+	groupWidth := (chartWidth - chartPadding - 10) / float64(len(labels))
+	barWidth := groupWidth * 0.8 / float64(len(series))
+	for i, label := range labels {
+		groupX := chart.Left + float64(i)*groupWidth + groupWidth*0.1
+		chart.Labels = append(chart.Labels, Label{X: groupX + groupWidth*0.4, Text: label})
+
+		for j, s := range series {
+			if i >= len(s.Values) {
+				continue
+			}
+			chart.Bars = append(chart.Bars, Bar{
+				X:      groupX + float64(j)*barWidth,
+				Y:      y(s.Values[i]),
+				Width:  barWidth,
+				Height: chart.Bottom - y(s.Values[i]),
+				Color:  s.Color,
+				Title:  s.Name + " " + label + ": " + strconv.FormatFloat(math.Round(s.Values[i]), 'f', -1, 64),
+			})
+		}
+	}
+
+	if target > 0 {
+		chart.TargetY = y(target)
+		chart.Target = strconv.FormatFloat(math.Round(target), 'f', -1, 64)
+	}
+
+	return chart
+}
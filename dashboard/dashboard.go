@@ -0,0 +1,160 @@
+package dashboard
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/analytics"
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var funcs = template.FuncMap{
+	"number":  func(value float64) string { return strconv.FormatFloat(value, 'f', 0, 64) },
+	"decimal": func(value float64) string { return strconv.FormatFloat(value, 'f', 1, 64) },
+	"percent": func(value float64) string { return strconv.FormatFloat(value*100, 'f', 0, 64) + "%" },
+	"datetime": func(value time.Time) string {
+		if value.IsZero() {
+			return "never"
+		}
+		return value.Format("02/01/2006 15:04")
+	},
+}
+
+var (
+	indexTemplate = template.Must(template.New("layout.html").Funcs(funcs).ParseFS(templateFiles, "templates/layout.html", "templates/index.html"))
+	userTemplate  = template.Must(template.New("layout.html").Funcs(funcs).ParseFS(templateFiles, "templates/layout.html", "templates/user.html"))
+)
+
+type UserRow struct {
+	Username    string
+	Consistency analytics.ConsistencyReport
+	Calories    float64
+	Protein     float64
+}
+
+type IndexPage struct {
+	Title  string
+	From   string
+	To     string
+	Status scraper.ScrapeStatus
+	Users  []UserRow
+}
+
+type DayRow struct {
+	Date          string
+	Calories      float64
+	Fat           float64
+	Carbs         float64
+	Protein       float64
+	Meals         int
+	Items         int
+	Discrepancies int
+	Anomalies     int
+}
+
+type UserPage struct {
+	Title        string
+	Username     string
+	From         string
+	To           string
+	Targets      scraper.Targets
+	Consistency  analytics.ConsistencyReport
+	CalorieChart Chart
+	MacroChart   Chart
+	Days         []DayRow
+	Anomalies    []scraper.Anomaly
+}
+
+// RenderIndex writes the overview of every user between from and to.
+// entries are keyed by username.
+func RenderIndex(w io.Writer, status scraper.ScrapeStatus, users []scraper.User, entries map[string][]scraper.DiaryEntry, from, to time.Time) error {
+	page := IndexPage{
+		Title:  "Diaries",
+		From:   from.Format("02/01/2006"),
+		To:     to.Format("02/01/2006"),
+		Status: status,
+		Users:  []UserRow{},
+	}
+
+	for _, user := range users {
+		stats := analytics.ComputeStats(user.Username, analytics.PeriodDay, from, to, entries[user.Username])
+		page.Users = append(page.Users, UserRow{
+			Username:    user.Username,
+			Consistency: analytics.ComputeConsistency(user.Username, from, to, entries[user.Username]),
+			Calories:    stats.Overall.Calories.Average,
+			Protein:     stats.Overall.Protein.Average,
+		})
+	}
+
+	return indexTemplate.Execute(w, page)
+}
+
+// RenderUser writes one user's weekly charts and daily diary table, newest
+// day first.
+func RenderUser(w io.Writer, user scraper.User, entries []scraper.DiaryEntry, anomalies []scraper.Anomaly, from, to time.Time) error {
+	logged := analytics.LoggedEntries(entries)
+	targets, _ := analytics.TargetsFor(user, logged)
+
+	page := UserPage{
+		Title:       user.Username,
+		Username:    user.Username,
+		From:        from.Format("02/01/2006"),
+		To:          to.Format("02/01/2006"),
+		Targets:     targets,
+		Consistency: analytics.ComputeConsistency(user.Username, from, to, entries),
+		Days:        []DayRow{},
+		Anomalies:   anomalies,
+	}
+
+	stats := analytics.ComputeStats(user.Username, analytics.PeriodWeek, from, to, entries)
+	labels := []string{}
+	calories := Series{Name: "Calories", Color: "#e07a2f"}
+	protein := Series{Name: "Protein", Color: "#3b7dd8"}
+	fat := Series{Name: "Fat", Color: "#d8b23b"}
+	carbs := Series{Name: "Carbs", Color: "#5aa85a"}
+	for _, week := range stats.Periods {
+		start, _ := time.Parse("2006-01-02", week.Start)
+		labels = append(labels, start.Format("02/01"))
+		calories.Values = append(calories.Values, week.Calories.Average)
+		protein.Values = append(protein.Values, week.Protein.Average)
+		fat.Values = append(fat.Values, week.Fat.Average)
+		carbs.Values = append(carbs.Values, week.Carbs.Average)
+	}
+	page.CalorieChart = barChart("Average calories per week", labels, []Series{calories}, targets.Calories)
+	page.MacroChart = barChart("Average macros per week (g)", labels, []Series{protein, fat, carbs}, 0)
+
+	anomaliesByDate := make(map[string]int)
+	for _, anomaly := range anomalies {
+		anomaliesByDate[anomaly.Date]++
+	}
+
+	for _, entry := range slices.Backward(logged) {
+		day := DayRow{
+			Date:      entry.Date,
+			Calories:  scraper.ParseNumber(entry.Calories),
+			Fat:       scraper.ParseNumber(entry.Fat),
+			Carbs:     scraper.ParseNumber(entry.Carbs),
+			Protein:   scraper.ParseNumber(entry.Protein),
+			Anomalies: anomaliesByDate[entry.Date],
+		}
+		for _, meal := range entry.Meals {
+			if len(meal.Items) > 0 {
+				day.Meals++
+			}
+			day.Items += len(meal.Items)
+		}
+		if entry.Reconciliation != nil {
+			day.Discrepancies = len(entry.Reconciliation.Discrepancies)
+		}
+		page.Days = append(page.Days, day)
+	}
+
+	return userTemplate.Execute(w, page)
+}
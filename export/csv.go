@@ -0,0 +1,153 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+type Level string
+
+const (
+	LevelDay  Level = "day"
+	LevelMeal Level = "meal"
+	LevelFood Level = "food"
+)
+
+func ParseLevel(value string) (Level, error) {
+	switch Level(value) {
+	case "":
+		return LevelDay, nil
+	case LevelDay, LevelMeal, LevelFood:
+		return Level(value), nil
+	}
+	return "", fmt.Errorf("unknown level %q, use day, meal or food", value)
+}
+
+var (
+	dayColumns  = []string{"username", "date", "calories", "fat_g", "carbs_g", "protein_g", "rdi_percent", "calories_burned", "net_calories", "meals", "items"}
+	mealColumns = []string{"username", "date", "meal", "calories", "fat_g", "carbs_g", "protein_g", "items"}
+	foodColumns = []string{"username", "date", "meal", "food", "brand", "food_id", "quantity", "serving_amount", "serving_unit", "servings", "calories", "fat_g", "carbs_g", "protein_g"}
+)
+
+// Columns returns the header of a flattened table at the given level.
+func Columns(level Level) []string {
+	switch level {
+	case LevelMeal:
+		return mealColumns
+	case LevelFood:
+		return foodColumns
+	}
+	return dayColumns
+}
+
+// number renders a scraped value as a plain decimal, leaving values the page
+// didn't show empty rather than zero.
+func number(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return ""
+	}
+	return strconv.FormatFloat(scraper.ParseNumber(value), 'f', -1, 64)
+}
+
+func decimal(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func isoDate(value string) string {
+	date, err := time.Parse("02/01/2006", value)
+	if err != nil {
+		return value
+	}
+	return date.Format("2006-01-02")
+}
+
+// Rows flattens a diary entry into the rows of the given level, in the order
+// of Columns.
+func Rows(level Level, username string, entry scraper.DiaryEntry) [][]string {
+	date := isoDate(entry.Date)
+
+	switch level {
+	case LevelMeal:
+		rows := [][]string{}
+		for _, meal := range entry.Meals {
+			rows = append(rows, []string{
+				username, date, meal.Name,
+				number(meal.Calories), number(meal.Fat), number(meal.Carbs), number(meal.Protein),
+				strconv.Itoa(len(meal.Items)),
+			})
+		}
+		return rows
+
+	case LevelFood:
+		rows := [][]string{}
+		for _, meal := range entry.Meals {
+			for _, item := range meal.Items {
+				rows = append(rows, []string{
+					username, date, meal.Name, item.Name, item.Brand, item.FoodID, item.Quantity,
+					decimal(item.ServingAmount), item.ServingUnit, decimal(item.Servings),
+					number(item.Calories), number(item.Fat), number(item.Carbs), number(item.Protein),
+				})
+			}
+		}
+		return rows
+	}
+
+	items := 0
+	for _, meal := range entry.Meals {
+		items += len(meal.Items)
+	}
+
+	burned := ""
+	if entry.Exercise != nil {
+		burned = number(entry.Exercise.Calories)
+	}
+
+	return [][]string{{
+		username, date,
+		number(entry.Calories), number(entry.Fat), number(entry.Carbs), number(entry.Protein),
+		number(strings.TrimSuffix(entry.IDR, "%")), burned, number(entry.NetCalories),
+		strconv.Itoa(len(entry.Meals)), strconv.Itoa(items),
+	}}
+}
+
+type flusher interface {
+	Flush()
+}
+
+// WriteCSV streams the stored entries of every user between from and to, with
+// the totals picked by mode. Each day is flushed as soon as it is written so
+// large ranges are never held in memory.
+func WriteCSV(w io.Writer, level Level, users []scraper.User, from, to time.Time, mode scraper.TotalsMode) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(Columns(level)); err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		err := scraper.ForEachDiaryEntry(user, from, to, func(entry scraper.DiaryEntry) error {
+			if err := writer.WriteAll(Rows(level, user.Username, scraper.ApplyEntryTotals(mode, entry))); err != nil {
+				return err
+			}
+			if f, ok := w.(flusher); ok {
+				f.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,42 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+func TestWriteCSVTotals(t *testing.T) {
+	writeTestEntries(t, testUser, testEntries)
+
+	day := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	for mode, want := range map[scraper.TotalsMode]string{
+		scraper.TotalsScraped:  "1845",
+		scraper.TotalsComputed: "700",
+	} {
+		var buf bytes.Buffer
+		if err := WriteCSV(&buf, LevelDay, []scraper.User{testUser}, day, day, mode); err != nil {
+			t.Fatalf("WriteCSV(%s): %v", mode, err)
+		}
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("%s: got %d records, want the header and one day", mode, len(records))
+		}
+
+		column := slices.Index(records[0], "calories")
+		if column < 0 {
+			t.Fatalf("no calories column in %q", records[0])
+		}
+		if got := records[1][column]; got != want {
+			t.Errorf("%s: day calories = %q, want %q", mode, got, want)
+		}
+	}
+}
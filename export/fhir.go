@@ -0,0 +1,221 @@
+package export
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+// FHIR R5 resources, limited to the elements the exporter fills in.
+
+type CodeableConcept struct {
+	Text string `json:"text"`
+}
+
+type CodeableReference struct {
+	Concept CodeableConcept `json:"concept"`
+}
+
+type Reference struct {
+	Reference string `json:"reference"`
+	Display   string `json:"display,omitempty"`
+}
+
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+type ConsumedItem struct {
+	Type             CodeableConcept   `json:"type"`
+	NutritionProduct CodeableReference `json:"nutritionProduct"`
+	Amount           *Quantity         `json:"amount,omitempty"`
+}
+
+type IngredientLabel struct {
+	Nutrient CodeableReference `json:"nutrient"`
+	Amount   Quantity          `json:"amount"`
+}
+
+type NutritionIntake struct {
+	ResourceType       string            `json:"resourceType"`
+	ID                 string            `json:"id"`
+	Status             string            `json:"status"`
+	Code               CodeableConcept   `json:"code"`
+	Subject            Reference         `json:"subject"`
+	OccurrenceDateTime string            `json:"occurrenceDateTime"`
+	ReportedBoolean    bool              `json:"reportedBoolean"`
+	ConsumedItem       []ConsumedItem    `json:"consumedItem"`
+	IngredientLabel    []IngredientLabel `json:"ingredientLabel,omitempty"`
+}
+
+type BundleEntry struct {
+	FullURL  string          `json:"fullUrl"`
+	Resource NutritionIntake `json:"resource"`
+}
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Timestamp    string        `json:"timestamp"`
+	Entry        []BundleEntry `json:"entry"`
+}
+
+const ucumSystem = "http://unitsofmeasure.org"
+
+var ucumCodes = map[string]string{"g": "g", "kg": "kg", "ml": "mL", "l": "L", "kcal": "kcal"}
+
+func quantity(value float64, unit string) Quantity {
+	q := Quantity{Value: value, Unit: unit}
+	if code, ok := ucumCodes[unit]; ok {
+		q.System = ucumSystem
+		q.Code = code
+	}
+	return q
+}
+
+func nutrientLabels(calories, fat, carbs, protein string) []IngredientLabel {
+	labels := []IngredientLabel{}
+
+	for _, nutrient := range []struct{ name, value, unit string }{
+		{"Energy", calories, "kcal"},
+		{"Fat", fat, "g"},
+		{"Carbohydrate", carbs, "g"},
+		{"Protein", protein, "g"},
+	} {
+		if nutrient.value == "" {
+			continue
+		}
+		labels = append(labels, IngredientLabel{
+			Nutrient: CodeableReference{Concept: CodeableConcept{Text: nutrient.name}},
+			Amount:   quantity(scraper.ParseNumber(nutrient.value), nutrient.unit),
+		})
+	}
+
+	return labels
+}
+
+func consumedItem(item scraper.FoodItem) ConsumedItem {
+	consumed := ConsumedItem{
+		Type:             CodeableConcept{Text: "Food"},
+		NutritionProduct: CodeableReference{Concept: CodeableConcept{Text: item.Name}},
+	}
+
+	switch {
+	case item.ServingAmount > 0 && item.ServingUnit != "":
+		amount := quantity(item.ServingAmount, item.ServingUnit)
+		consumed.Amount = &amount
+	case item.Servings > 0:
+		amount := quantity(item.Servings, "serving")
+		consumed.Amount = &amount
+	}
+
+	return consumed
+}
+
+// fhirIDPattern matches what FHIR allows in a resource id.
+var fhirIDPattern = regexp.MustCompile(`^[A-Za-z0-9\-.]{1,64}$`)
+
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9\-.]+`)
+
+// maxPatientIDLength leaves room in NutritionIntake ids for the date and
+// meal number that follow the patient id.
+const maxPatientIDLength = 48
+
+// patientID turns a username into a valid FHIR id. Usernames that need
+// changing get a short hash of the original appended so two of them can't
+// end up with the same id.
+func patientID(username string) string {
+	if len(username) <= maxPatientIDLength && fhirIDPattern.MatchString(username) {
+		return username
+	}
+
+	sum := sha256.Sum256([]byte(username))
+	suffix := "-" + hex.EncodeToString(sum[:4])
+
+	id := invalidIDChars.ReplaceAllString(username, "-")
+	if len(id) > maxPatientIDLength-len(suffix) {
+		id = id[:maxPatientIDLength-len(suffix)]
+	}
+	return id + suffix
+}
+
+// resourceURN returns a stable urn:uuid for a resource, a name-based (version
+// 5) UUID of its type and id, so exporting the same day twice gives the same
+// fullUrl.
+func resourceURN(resourceType, id string) string {
+	sum := sha1.Sum([]byte("fatsecret-scrapper/" + resourceType + "/" + id))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+
+	hexSum := hex.EncodeToString(sum[:16])
+	return fmt.Sprintf("urn:uuid:%s-%s-%s-%s-%s", hexSum[0:8], hexSum[8:12], hexSum[12:16], hexSum[16:20], hexSum[20:32])
+}
+
+// NutritionIntakes maps each logged meal of an entry to a NutritionIntake:
+// its foods become consumed items and the meal totals ingredient labels.
+func NutritionIntakes(username string, entry scraper.DiaryEntry) []NutritionIntake {
+	date := isoDate(entry.Date)
+	patient := patientID(username)
+	intakes := []NutritionIntake{}
+
+	for i, meal := range entry.Meals {
+		if len(meal.Items) == 0 {
+			continue
+		}
+
+		intake := NutritionIntake{
+			ResourceType:       "NutritionIntake",
+			ID:                 fmt.Sprintf("%s-%s-%d", patient, date, i+1),
+			Status:             "completed",
+			Code:               CodeableConcept{Text: meal.Name},
+			Subject:            Reference{Reference: "Patient/" + patient, Display: username},
+			OccurrenceDateTime: date,
+			ReportedBoolean:    true,
+			ConsumedItem:       []ConsumedItem{},
+			IngredientLabel:    nutrientLabels(meal.Calories, meal.Fat, meal.Carbs, meal.Protein),
+		}
+
+		for _, item := range meal.Items {
+			intake.ConsumedItem = append(intake.ConsumedItem, consumedItem(item))
+		}
+
+		intakes = append(intakes, intake)
+	}
+
+	return intakes
+}
+
+// FHIRBundle returns a collection Bundle of NutritionIntake resources for the
+// stored entries of the user between from and to, with the totals picked by
+// mode.
+func FHIRBundle(user scraper.User, from, to time.Time, mode scraper.TotalsMode) (Bundle, error) {
+	bundle := Bundle{
+		ResourceType: "Bundle",
+		Type:         "collection",
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Entry:        []BundleEntry{},
+	}
+
+	err := scraper.ForEachDiaryEntry(user, from, to, func(entry scraper.DiaryEntry) error {
+		for _, intake := range NutritionIntakes(user.Username, scraper.ApplyEntryTotals(mode, entry)) {
+			bundle.Entry = append(bundle.Entry, BundleEntry{
+				FullURL:  resourceURN(intake.ResourceType, intake.ID),
+				Resource: intake,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	return bundle, nil
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+var urnUUIDPattern = regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestFHIRBundleIDs(t *testing.T) {
+	user := scraper.User{Username: "ana_maria@example.com", ID: "1"}
+	writeTestEntries(t, user, testEntries)
+
+	from := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 13, 0, 0, 0, 0, time.UTC)
+	bundle, err := FHIRBundle(user, from, to, scraper.TotalsScraped)
+	if err != nil {
+		t.Fatalf("FHIRBundle: %v", err)
+	}
+	if len(bundle.Entry) != 1 {
+		t.Fatalf("got %d entries, want the one logged meal", len(bundle.Entry))
+	}
+
+	entry := bundle.Entry[0]
+	if !urnUUIDPattern.MatchString(entry.FullURL) {
+		t.Errorf("fullUrl %q is not a urn:uuid", entry.FullURL)
+	}
+	if !fhirIDPattern.MatchString(entry.Resource.ID) {
+		t.Errorf("resource id %q is not a valid FHIR id", entry.Resource.ID)
+	}
+	if !strings.HasSuffix(entry.Resource.ID, "-2025-05-12-1") {
+		t.Errorf("resource id %q does not end with the date and meal", entry.Resource.ID)
+	}
+	if got := strings.TrimPrefix(entry.Resource.Subject.Reference, "Patient/"); !fhirIDPattern.MatchString(got) {
+		t.Errorf("subject reference %q has an invalid id", entry.Resource.Subject.Reference)
+	}
+	if entry.Resource.Subject.Display != user.Username {
+		t.Errorf("subject display = %q, want the username", entry.Resource.Subject.Display)
+	}
+
+	again, err := FHIRBundle(user, from, to, scraper.TotalsScraped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Entry[0].FullURL != entry.FullURL {
+		t.Error("fullUrl changed between exports of the same day")
+	}
+}
+
+func TestPatientID(t *testing.T) {
+	if got := patientID("ana.silva-2"); got != "ana.silva-2" {
+		t.Errorf("patientID changed a valid username to %q", got)
+	}
+
+	seen := make(map[string]string)
+	for _, username := range []string{"ana_b", "ana b", "ana@b", "joão", strings.Repeat("x", 80)} {
+		id := patientID(username)
+		if !fhirIDPattern.MatchString(id) || len(id) > maxPatientIDLength {
+			t.Errorf("patientID(%q) = %q, not a valid id", username, id)
+		}
+		if other, ok := seen[id]; ok {
+			t.Errorf("%q and %q both map to %q", username, other, id)
+		}
+		seen[id] = username
+	}
+}
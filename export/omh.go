@@ -0,0 +1,116 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+// Open mHealth data points. There is no official intake schema, so meals are
+// published under our own namespace using the standard header and
+// unit-value conventions.
+
+type SchemaID struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+}
+
+type AcquisitionProvenance struct {
+	SourceName string `json:"source_name"`
+	Modality   string `json:"modality"`
+}
+
+type DataPointHeader struct {
+	ID                    string                `json:"id"`
+	CreationDateTime      string                `json:"creation_date_time"`
+	SchemaID              SchemaID              `json:"schema_id"`
+	AcquisitionProvenance AcquisitionProvenance `json:"acquisition_provenance"`
+	UserID                string                `json:"user_id"`
+}
+
+type UnitValue struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+type TimeFrame struct {
+	Date string `json:"date"`
+}
+
+type MealIntakeBody struct {
+	EffectiveTimeFrame TimeFrame  `json:"effective_time_frame"`
+	MealType           string     `json:"meal_type"`
+	Foods              []string   `json:"foods"`
+	CaloriesConsumed   *UnitValue `json:"calories_consumed,omitempty"`
+	Fat                *UnitValue `json:"fat,omitempty"`
+	Carbohydrate       *UnitValue `json:"carbohydrate,omitempty"`
+	Protein            *UnitValue `json:"protein,omitempty"`
+}
+
+type DataPoint struct {
+	Header DataPointHeader `json:"header"`
+	Body   MealIntakeBody  `json:"body"`
+}
+
+var mealIntakeSchema = SchemaID{Namespace: "fatsecret", Name: "meal-intake", Version: "1.0"}
+
+func unitValue(value, unit string) *UnitValue {
+	if value == "" {
+		return nil
+	}
+	return &UnitValue{Value: scraper.ParseNumber(value), Unit: unit}
+}
+
+// OpenMHealthDataPoints returns one data point per logged meal for the stored
+// entries of the user between from and to, with the totals picked by mode.
+// Like the FHIR export, the user is identified by patientID and each header
+// id is a stable UUID, so exporting a day twice gives the same ids.
+func OpenMHealthDataPoints(user scraper.User, from, to time.Time, mode scraper.TotalsMode) ([]DataPoint, error) {
+	points := []DataPoint{}
+	now := time.Now().UTC().Format(time.RFC3339)
+	userID := patientID(user.Username)
+
+	err := scraper.ForEachDiaryEntry(user, from, to, func(entry scraper.DiaryEntry) error {
+		entry = scraper.ApplyEntryTotals(mode, entry)
+		date := isoDate(entry.Date)
+
+		for i, meal := range entry.Meals {
+			if len(meal.Items) == 0 {
+				continue
+			}
+
+			foods := []string{}
+			for _, item := range meal.Items {
+				foods = append(foods, item.Name)
+			}
+
+			points = append(points, DataPoint{
+				Header: DataPointHeader{
+					ID:                    strings.TrimPrefix(resourceURN(mealIntakeSchema.Name, fmt.Sprintf("%s-%s-%d", userID, date, i+1)), "urn:uuid:"),
+					CreationDateTime:      now,
+					SchemaID:              mealIntakeSchema,
+					AcquisitionProvenance: AcquisitionProvenance{SourceName: "FatSecret", Modality: "self-reported"},
+					UserID:                userID,
+				},
+				Body: MealIntakeBody{
+					EffectiveTimeFrame: TimeFrame{Date: date},
+					MealType:           meal.Name,
+					Foods:              foods,
+					CaloriesConsumed:   unitValue(meal.Calories, "kcal"),
+					Fat:                unitValue(meal.Fat, "g"),
+					Carbohydrate:       unitValue(meal.Carbs, "g"),
+					Protein:            unitValue(meal.Protein, "g"),
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
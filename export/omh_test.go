@@ -0,0 +1,65 @@
+package export
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestOpenMHealthDataPoints(t *testing.T) {
+	user := scraper.User{Username: "ana_maria@example.com", ID: "1"}
+	writeTestEntries(t, user, testEntries)
+
+	from := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 13, 0, 0, 0, 0, time.UTC)
+	points, err := OpenMHealthDataPoints(user, from, to, scraper.TotalsScraped)
+	if err != nil {
+		t.Fatalf("OpenMHealthDataPoints: %v", err)
+	}
+	if len(points) != 1 {
+		t.Fatalf("got %d data points, want the one logged meal", len(points))
+	}
+
+	point := points[0]
+	if !uuidPattern.MatchString(point.Header.ID) {
+		t.Errorf("header id %q is not a UUID", point.Header.ID)
+	}
+	if point.Header.UserID != patientID(user.Username) {
+		t.Errorf("user_id = %q, want the patient id %q", point.Header.UserID, patientID(user.Username))
+	}
+	if point.Header.SchemaID != mealIntakeSchema {
+		t.Errorf("schema_id = %+v", point.Header.SchemaID)
+	}
+
+	body := point.Body
+	if body.EffectiveTimeFrame.Date != "2025-05-12" || body.MealType != "Almoço" {
+		t.Errorf("body = %+v, want lunch on 2025-05-12", body)
+	}
+	if !slices.Equal(body.Foods, []string{"Feijão & arroz <caseiro>", "Bife"}) {
+		t.Errorf("foods = %q", body.Foods)
+	}
+	if *body.CaloriesConsumed != (UnitValue{Value: 700, Unit: "kcal"}) || *body.Protein != (UnitValue{Value: 45, Unit: "g"}) {
+		t.Errorf("calories %+v, protein %+v", body.CaloriesConsumed, body.Protein)
+	}
+
+	again, err := OpenMHealthDataPoints(user, from, to, scraper.TotalsScraped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0].Header.ID != point.Header.ID {
+		t.Error("header id changed between exports of the same day")
+	}
+
+	other, err := FHIRBundle(user, from, to, scraper.TotalsScraped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Entry[0].Resource.Subject.Reference != "Patient/"+point.Header.UserID {
+		t.Errorf("FHIR subject %q and Open mHealth user_id %q differ", other.Entry[0].Resource.Subject.Reference, point.Header.UserID)
+	}
+}
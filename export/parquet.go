@@ -0,0 +1,378 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+// The writer below covers the small part of the Parquet format the exports
+// need: one row group per file, one uncompressed PLAIN data page per column
+// and optional (nullable) columns only.
+
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetOptional = 1
+	parquetUTF8     = 0
+	parquetDate     = 6
+
+	parquetPlain = 0
+	parquetRLE   = 3
+)
+
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// intColumns are counts stored as INT64; other non-text columns are DOUBLE.
+var intColumns = map[string]bool{"meals": true, "items": true}
+
+type thriftWriter struct {
+	buf    bytes.Buffer
+	fields []int16
+}
+
+func (t *thriftWriter) varint(value uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, value))
+}
+
+func (t *thriftWriter) zigzag(value int64) {
+	t.varint(uint64((value << 1) ^ (value >> 63)))
+}
+
+func (t *thriftWriter) field(id int16, kind byte) {
+	last := &t.fields[len(t.fields)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		t.buf.WriteByte(kind)
+		t.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) beginStruct() {
+	t.fields = append(t.fields, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0)
+	t.fields = t.fields[:len(t.fields)-1]
+}
+
+func (t *thriftWriter) i32(id int16, value int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(value))
+}
+
+func (t *thriftWriter) i64(id int16, value int64) {
+	t.field(id, thriftI64)
+	t.zigzag(value)
+}
+
+func (t *thriftWriter) binaryValue(value string) {
+	t.varint(uint64(len(value)))
+	t.buf.WriteString(value)
+}
+
+func (t *thriftWriter) str(id int16, value string) {
+	t.field(id, thriftBinary)
+	t.binaryValue(value)
+}
+
+func (t *thriftWriter) list(id int16, kind byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | kind)
+	} else {
+		t.buf.WriteByte(0xF0 | kind)
+		t.varint(uint64(size))
+	}
+}
+
+type parquetColumn struct {
+	name    string
+	kind    int32
+	logical int32
+	present []bool
+	values  bytes.Buffer
+}
+
+func newParquetColumn(name string) *parquetColumn {
+	column := &parquetColumn{name: name, logical: -1}
+	switch {
+	case name == "date":
+		column.kind, column.logical = parquetInt32, parquetDate
+	case textColumns[name]:
+		column.kind, column.logical = parquetByteArray, parquetUTF8
+	case intColumns[name]:
+		column.kind = parquetInt64
+	default:
+		column.kind = parquetDouble
+	}
+	return column
+}
+
+func (c *parquetColumn) add(value string) {
+	if value == "" {
+		c.present = append(c.present, false)
+		return
+	}
+
+	switch c.kind {
+	case parquetByteArray:
+		c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(value))))
+		c.values.WriteString(value)
+	case parquetInt32:
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.present = append(c.present, false)
+			return
+		}
+		days := int32(date.Unix() / 86400)
+		c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(days)))
+	case parquetInt64:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.present = append(c.present, false)
+			return
+		}
+		c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(number)))
+	case parquetDouble:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.present = append(c.present, false)
+			return
+		}
+		c.values.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(number)))
+	}
+
+	c.present = append(c.present, true)
+}
+
+// definitionLevels encodes which rows are present with the RLE/bit-packed
+// hybrid encoding, using RLE runs only, prefixed by its length.
+func (c *parquetColumn) definitionLevels() []byte {
+	var runs []byte
+	for i := 0; i < len(c.present); {
+		j := i
+		for j < len(c.present) && c.present[j] == c.present[i] {
+			j++
+		}
+
+		runs = binary.AppendUvarint(runs, uint64(j-i)<<1)
+		if c.present[i] {
+			runs = append(runs, 1)
+		} else {
+			runs = append(runs, 0)
+		}
+		i = j
+	}
+
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(runs))), runs...)
+}
+
+func (c *parquetColumn) page() ([]byte, []byte) {
+	data := append(c.definitionLevels(), c.values.Bytes()...)
+
+	header := &thriftWriter{}
+	header.beginStruct()
+	header.i32(1, 0)
+	header.i32(2, int32(len(data)))
+	header.i32(3, int32(len(data)))
+	header.field(5, thriftStruct)
+	header.beginStruct()
+	header.i32(1, int32(len(c.present)))
+	header.i32(2, parquetPlain)
+	header.i32(3, parquetRLE)
+	header.i32(4, parquetRLE)
+	header.endStruct()
+	header.endStruct()
+
+	return header.buf.Bytes(), data
+}
+
+type columnChunk struct {
+	offset int64
+	size   int64
+}
+
+func writeParquet(w io.Writer, columns []string, rows [][]string) error {
+	parquetColumns := make([]*parquetColumn, len(columns))
+	for i, name := range columns {
+		parquetColumns[i] = newParquetColumn(name)
+	}
+	for _, row := range rows {
+		for i, value := range row {
+			parquetColumns[i].add(value)
+		}
+	}
+
+	var file bytes.Buffer
+	file.WriteString("PAR1")
+
+	chunks := make([]columnChunk, len(parquetColumns))
+	for i, column := range parquetColumns {
+		header, data := column.page()
+		chunks[i] = columnChunk{offset: int64(file.Len()), size: int64(len(header) + len(data))}
+		file.Write(header)
+		file.Write(data)
+	}
+
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.size
+	}
+
+	meta := &thriftWriter{}
+	meta.beginStruct()
+	meta.i32(1, 1)
+
+	meta.list(2, thriftStruct, len(parquetColumns)+1)
+	meta.beginStruct()
+	meta.str(4, "schema")
+	meta.i32(5, int32(len(parquetColumns)))
+	meta.endStruct()
+	for _, column := range parquetColumns {
+		meta.beginStruct()
+		meta.i32(1, column.kind)
+		meta.i32(3, parquetOptional)
+		meta.str(4, column.name)
+		if column.logical >= 0 {
+			meta.i32(6, column.logical)
+		}
+		meta.endStruct()
+	}
+
+	meta.i64(3, int64(len(rows)))
+
+	meta.list(4, thriftStruct, 1)
+	meta.beginStruct()
+	meta.list(1, thriftStruct, len(parquetColumns))
+	for i, column := range parquetColumns {
+		meta.beginStruct()
+		meta.i64(2, chunks[i].offset)
+		meta.field(3, thriftStruct)
+		meta.beginStruct()
+		meta.i32(1, column.kind)
+		meta.list(2, thriftI32, 2)
+		meta.zigzag(parquetPlain)
+		meta.zigzag(parquetRLE)
+		meta.list(3, thriftBinary, 1)
+		meta.binaryValue(column.name)
+		meta.i32(4, 0)
+		meta.i64(5, int64(len(column.present)))
+		meta.i64(6, chunks[i].size)
+		meta.i64(7, chunks[i].size)
+		meta.i64(9, chunks[i].offset)
+		meta.endStruct()
+		meta.endStruct()
+	}
+	meta.i64(2, totalSize)
+	meta.i64(3, int64(len(rows)))
+	meta.endStruct()
+
+	meta.str(6, "fatsecret-scrapper")
+	meta.endStruct()
+
+	file.Write(meta.buf.Bytes())
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(meta.buf.Len())))
+	file.WriteString("PAR1")
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// hivePartitionValue escapes a partition value the way Hive does, so a
+// username can't add directories or break the key=value segment.
+func hivePartitionValue(value string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		if b < 0x20 || b == 0x7f || strings.IndexByte("\"#%'*/:=?\\{[]^", b) >= 0 {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+			continue
+		}
+		escaped.WriteByte(b)
+	}
+	return escaped.String()
+}
+
+// ParquetTables are the flat tables written by WriteParquet, keyed by the
+// directory they are written under.
+var ParquetTables = map[string]Level{
+	"days":  LevelDay,
+	"foods": LevelFood,
+}
+
+// WriteParquet writes the day and food tables for the stored entries between
+// from and to, with the totals picked by mode, partitioned Hive-style as
+// {table}/user={username}/month={YYYY-MM}/part-0.parquet, with the username
+// escaped as Hive does. create is called once per partition file with its
+// slash-separated path.
+func WriteParquet(create func(path string) (io.WriteCloser, error), users []scraper.User, from, to time.Time, mode scraper.TotalsMode) error {
+	for _, user := range users {
+		month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+		for !month.After(to) {
+			start := month
+			if start.Before(from) {
+				start = from
+			}
+			end := month.AddDate(0, 1, -1)
+			if end.After(to) {
+				end = to
+			}
+
+			tables := make(map[string][][]string)
+			err := scraper.ForEachDiaryEntry(user, start, end, func(entry scraper.DiaryEntry) error {
+				entry = scraper.ApplyEntryTotals(mode, entry)
+				for table, level := range ParquetTables {
+					tables[table] = append(tables[table], Rows(level, user.Username, entry)...)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for table, level := range ParquetTables {
+				if len(tables[table]) == 0 {
+					continue
+				}
+
+				path := fmt.Sprintf("%s/user=%s/month=%s/part-0.parquet", table, hivePartitionValue(user.Username), month.Format("2006-01"))
+				file, err := create(path)
+				if err != nil {
+					return fmt.Errorf("failed to create %s: %v", path, err)
+				}
+
+				if err := writeParquet(file, Columns(level), tables[table]); err != nil {
+					file.Close()
+					return fmt.Errorf("failed to write %s: %v", path, err)
+				}
+				if err := file.Close(); err != nil {
+					return err
+				}
+			}
+
+			month = month.AddDate(0, 1, 0)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// parquetBytes serves a written file to parquet-go's reader, which opens it
+// again for every column.
+type parquetBytes struct {
+	*bytes.Reader
+	data []byte
+}
+
+func (f parquetBytes) Open(string) (source.ParquetFile, error) {
+	return parquetBytes{bytes.NewReader(f.data), f.data}, nil
+}
+
+func (parquetBytes) Create(string) (source.ParquetFile, error) {
+	return nil, errors.New("read only")
+}
+
+func (parquetBytes) Write([]byte) (int, error) {
+	return 0, errors.New("read only")
+}
+
+func (parquetBytes) Close() error { return nil }
+
+type parquetField struct {
+	name      string
+	kind      parquet.Type
+	converted *parquet.ConvertedType
+}
+
+// readParquetFile reads a file back with parquet-go into its schema and rows
+// of strings, formatted the way Rows formats them. Nulls become "".
+func readParquetFile(t *testing.T, data []byte) ([]parquetField, [][]string) {
+	t.Helper()
+
+	pr, err := reader.NewParquetReader(parquetBytes{bytes.NewReader(data), data}, nil, 1)
+	if err != nil {
+		t.Fatalf("parquet-go can't read the file: %v", err)
+	}
+	defer pr.ReadStop()
+
+	// The reader renames columns to Go field names; ExName keeps the name
+	// stored in the file.
+	fields := []parquetField{}
+	for i, element := range pr.SchemaHandler.SchemaElements[1:] {
+		name := pr.SchemaHandler.GetExName(i + 1)
+		if element.GetRepetitionType() != parquet.FieldRepetitionType_OPTIONAL {
+			t.Errorf("column %s is not OPTIONAL", name)
+		}
+		fields = append(fields, parquetField{name: name, kind: element.GetType(), converted: element.ConvertedType})
+	}
+
+	numRows := pr.GetNumRows()
+	rows := make([][]string, numRows)
+	for i := range rows {
+		rows[i] = make([]string, len(fields))
+	}
+
+	for c, field := range fields {
+		values, _, levels, err := pr.ReadColumnByIndex(int64(c), numRows)
+		if err != nil {
+			t.Fatalf("reading column %s: %v", field.name, err)
+		}
+		if len(values) != int(numRows) {
+			t.Fatalf("column %s has %d values for %d rows", field.name, len(values), numRows)
+		}
+
+		for r, value := range values {
+			if levels[r] == 0 {
+				continue
+			}
+			switch value := value.(type) {
+			case int32:
+				if field.converted == nil || *field.converted != parquet.ConvertedType_DATE {
+					t.Fatalf("column %s is INT32 without the DATE type", field.name)
+				}
+				rows[r][c] = time.Unix(int64(value)*86400, 0).UTC().Format("2006-01-02")
+			case int64:
+				rows[r][c] = strconv.FormatInt(value, 10)
+			case float64:
+				rows[r][c] = strconv.FormatFloat(value, 'f', -1, 64)
+			case string:
+				rows[r][c] = value
+			default:
+				t.Fatalf("column %s has a %T value", field.name, value)
+			}
+		}
+	}
+
+	return fields, rows
+}
+
+func TestWriteParquetRoundTrip(t *testing.T) {
+	for _, level := range []Level{LevelDay, LevelFood} {
+		rows := [][]string{}
+		for _, entry := range testEntries {
+			rows = append(rows, Rows(level, testUser.Username, entry)...)
+		}
+
+		var buf bytes.Buffer
+		if err := writeParquet(&buf, Columns(level), rows); err != nil {
+			t.Fatalf("writeParquet(%s): %v", level, err)
+		}
+
+		fields, got := readParquetFile(t, buf.Bytes())
+
+		names := []string{}
+		for _, field := range fields {
+			names = append(names, field.name)
+		}
+		if !slices.Equal(names, Columns(level)) {
+			t.Errorf("%s schema = %v, want %v", level, names, Columns(level))
+		}
+		for _, field := range fields {
+			if textColumns[field.name] && field.name != "date" && (field.kind != parquet.Type_BYTE_ARRAY || field.converted == nil || *field.converted != parquet.ConvertedType_UTF8) {
+				t.Errorf("%s column %s is not a UTF8 byte array", level, field.name)
+			}
+		}
+
+		if len(got) != len(rows) {
+			t.Fatalf("%s: read %d rows, wrote %d", level, len(got), len(rows))
+		}
+		for i := range rows {
+			if !slices.Equal(got[i], rows[i]) {
+				t.Errorf("%s row %d = %q, want %q", level, i, got[i], rows[i])
+			}
+		}
+	}
+}
+
+type bufferFile struct {
+	bytes.Buffer
+	files map[string][]byte
+	path  string
+}
+
+func (f *bufferFile) Close() error {
+	f.files[f.path] = f.Bytes()
+	return nil
+}
+
+func TestWriteParquetPartitions(t *testing.T) {
+	user := scraper.User{Username: "ana b=c:x", ID: "1"}
+	writeTestEntries(t, user, testEntries)
+
+	files := make(map[string][]byte)
+	create := func(path string) (io.WriteCloser, error) {
+		return &bufferFile{files: files, path: path}, nil
+	}
+
+	from := time.Date(2025, time.April, 20, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 31, 0, 0, 0, 0, time.UTC)
+	if err := WriteParquet(create, []scraper.User{user}, from, to, scraper.TotalsScraped); err != nil {
+		t.Fatalf("WriteParquet: %v", err)
+	}
+
+	paths := []string{}
+	for path := range files {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	want := []string{
+		"days/user=ana b%3Dc%3Ax/month=2025-05/part-0.parquet",
+		"foods/user=ana b%3Dc%3Ax/month=2025-05/part-0.parquet",
+	}
+	if !slices.Equal(paths, want) {
+		t.Fatalf("partitions = %q, want %q", paths, want)
+	}
+
+	_, days := readParquetFile(t, files[want[0]])
+	if len(days) != 2 || days[0][0] != user.Username || days[1][1] != "2025-05-13" {
+		t.Errorf("days partition rows = %q", days)
+	}
+}
+
+func TestHivePartitionValue(t *testing.T) {
+	for value, want := range map[string]string{
+		"ana":        "ana",
+		"ana/../x":   "ana%2F..%2Fx",
+		"a=b":        "a%3Db",
+		"50%":        "50%25",
+		"joão silva": "joão silva",
+	} {
+		if got := hivePartitionValue(value); got != want {
+			t.Errorf("hivePartitionValue(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestWriteParquetUnparseableNumbers(t *testing.T) {
+	var buf bytes.Buffer
+	columns := []string{"username", "date", "meals", "calories"}
+	rows := [][]string{
+		{"ana", "2025-05-12", "3", "1845.5"},
+		{"ana", "not a date", "three", "n/a"},
+		{"ana", "2025-05-14", "", "0"},
+	}
+	if err := writeParquet(&buf, columns, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	_, got := readParquetFile(t, buf.Bytes())
+	want := [][]string{
+		{"ana", "2025-05-12", "3", "1845.5"},
+		{"ana", "", "", ""},
+		{"ana", "2025-05-14", "", "0"},
+	}
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Errorf("row %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
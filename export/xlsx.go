@@ -0,0 +1,325 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+// textColumns are written as strings; every other column is numeric.
+var textColumns = map[string]bool{
+	"username": true, "date": true, "week": true, "meal": true, "food": true,
+	"brand": true, "food_id": true, "quantity": true, "serving_unit": true,
+}
+
+// weeklyColumns are the daily columns averaged per week on the Weekly sheet.
+var weeklyColumns = []string{"calories", "fat_g", "carbs_g", "protein_g", "net_calories"}
+
+type sheet struct {
+	name string
+	rows [][]cell
+}
+
+type cell struct {
+	text    string
+	number  string
+	formula string
+}
+
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func typedRow(columns, values []string) []cell {
+	row := make([]cell, len(values))
+	for i, value := range values {
+		if textColumns[columns[i]] {
+			row[i] = cell{text: value}
+		} else {
+			row[i] = cell{number: value}
+		}
+	}
+	return row
+}
+
+func headerRow(columns []string) []cell {
+	row := make([]cell, len(columns))
+	for i, column := range columns {
+		row[i] = cell{text: column}
+	}
+	return row
+}
+
+func weekStart(value string) string {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return ""
+	}
+	offset := (int(date.Weekday()) + 6) % 7
+	return date.AddDate(0, 0, -offset).Format("2006-01-02")
+}
+
+// sharedStrings is the workbook's string table. Text cells refer to it by
+// index, which is what every spreadsheet reader supports.
+type sharedStrings struct {
+	index  map[string]int
+	values []string
+	count  int
+}
+
+func newSharedStrings() *sharedStrings {
+	return &sharedStrings{index: make(map[string]int)}
+}
+
+func (s *sharedStrings) add(value string) int {
+	s.count++
+	if i, ok := s.index[value]; ok {
+		return i
+	}
+	s.index[value] = len(s.values)
+	s.values = append(s.values, value)
+	return len(s.values) - 1
+}
+
+func (s *sharedStrings) xml() string {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="%d" uniqueCount="%d">`, s.count, len(s.values))
+	for _, value := range s.values {
+		buf.WriteString(`<si><t xml:space="preserve">`)
+		xml.EscapeText(&buf, []byte(value))
+		buf.WriteString(`</t></si>`)
+	}
+	buf.WriteString(`</sst>`)
+	return buf.String()
+}
+
+func (s sheet) writeXML(w io.Writer, shared *sharedStrings) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	for r, row := range s.rows {
+		fmt.Fprintf(&buf, `<row r="%d">`, r+1)
+		for c, value := range row {
+			ref := columnName(c) + strconv.Itoa(r+1)
+			switch {
+			case value.formula != "":
+				fmt.Fprintf(&buf, `<c r="%s"><f>`, ref)
+				xml.EscapeText(&buf, []byte(value.formula))
+				buf.WriteString(`</f>`)
+				if value.number != "" {
+					fmt.Fprintf(&buf, `<v>%s</v>`, value.number)
+				}
+				buf.WriteString(`</c>`)
+			case value.number != "":
+				fmt.Fprintf(&buf, `<c r="%s"><v>%s</v></c>`, ref, value.number)
+			case value.text != "":
+				style := ""
+				if r == 0 {
+					style = ` s="1"`
+				}
+				fmt.Fprintf(&buf, `<c r="%s" t="s"%s><v>%d</v></c>`, ref, style, shared.add(value.text))
+			}
+		}
+		buf.WriteString(`</row>`)
+	}
+
+	buf.WriteString(`</sheetData></worksheet>`)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// weeklySheet averages the daily sheet per week with AVERAGEIFS formulas,
+// caching the computed value so viewers that don't recalculate still show it.
+func weeklySheet(daily sheet, dailyColumns []string) sheet {
+	weekColumn := columnName(len(dailyColumns))
+
+	weeks := []string{}
+	sums := make(map[string][]float64)
+	counts := make(map[string][]int)
+	days := make(map[string]int)
+
+	for _, row := range daily.rows[1:] {
+		week := row[len(dailyColumns)].text
+		if _, ok := sums[week]; !ok {
+			weeks = append(weeks, week)
+			sums[week] = make([]float64, len(weeklyColumns))
+			counts[week] = make([]int, len(weeklyColumns))
+		}
+		days[week]++
+
+		for i, column := range weeklyColumns {
+			for c, name := range dailyColumns {
+				if name == column && row[c].number != "" {
+					value, _ := strconv.ParseFloat(row[c].number, 64)
+					sums[week][i] += value
+					counts[week][i]++
+				}
+			}
+		}
+	}
+
+	header := append([]string{"week", "days_logged"}, weeklyColumns...)
+	weekly := sheet{name: "Weekly", rows: [][]cell{headerRow(header)}}
+
+	for r, week := range weeks {
+		rowNumber := r + 2
+		row := []cell{
+			{text: week},
+			{
+				formula: fmt.Sprintf("COUNTIF(Daily!%s:%s,A%d)", weekColumn, weekColumn, rowNumber),
+				number:  strconv.Itoa(days[week]),
+			},
+		}
+
+		for i, column := range weeklyColumns {
+			dailyColumn := ""
+			for c, name := range dailyColumns {
+				if name == column {
+					dailyColumn = columnName(c)
+				}
+			}
+
+			value := ""
+			if counts[week][i] > 0 {
+				value = strconv.FormatFloat(sums[week][i]/float64(counts[week][i]), 'f', 2, 64)
+			}
+
+			row = append(row, cell{
+				formula: fmt.Sprintf(`IFERROR(AVERAGEIFS(Daily!%s:%s,Daily!%s:%s,A%d),"")`, dailyColumn, dailyColumn, weekColumn, weekColumn, rowNumber),
+				number:  value,
+			})
+		}
+
+		weekly.rows = append(weekly.rows, row)
+	}
+
+	return weekly
+}
+
+func buildSheets(username string, entries []scraper.DiaryEntry) []sheet {
+	dayColumns := Columns(LevelDay)
+
+	daily := sheet{name: "Daily", rows: [][]cell{headerRow(slices.Concat(dayColumns, []string{"week"}))}}
+	meals := sheet{name: "Meals", rows: [][]cell{headerRow(Columns(LevelMeal))}}
+	foods := sheet{name: "Foods", rows: [][]cell{headerRow(Columns(LevelFood))}}
+
+	for _, entry := range entries {
+		for _, values := range Rows(LevelDay, username, entry) {
+			row := typedRow(dayColumns, values)
+			row = append(row, cell{text: weekStart(values[1])})
+			daily.rows = append(daily.rows, row)
+		}
+		for _, values := range Rows(LevelMeal, username, entry) {
+			meals.rows = append(meals.rows, typedRow(Columns(LevelMeal), values))
+		}
+		for _, values := range Rows(LevelFood, username, entry) {
+			foods.rows = append(foods.rows, typedRow(Columns(LevelFood), values))
+		}
+	}
+
+	return []sheet{daily, meals, foods, weeklySheet(daily, dayColumns)}
+}
+
+const (
+	contentTypesXML = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`<Override PartName="/xl/sharedStrings.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sharedStrings+xml"/>` +
+		`%s</Types>`
+
+	rootRelsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	stylesXML = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border/></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`</styleSheet>`
+)
+
+func writeZipFile(archive *zip.Writer, name, content string) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(file, content)
+	return err
+}
+
+// WriteXLSX writes one workbook for the user with Daily, Meals and Foods
+// sheets over the stored entries between from and to, with the totals picked
+// by mode, plus a Weekly sheet whose averages are formulas over the Daily
+// sheet.
+func WriteXLSX(w io.Writer, user scraper.User, from, to time.Time, mode scraper.TotalsMode) error {
+	entries, err := scraper.LoadDiaryEntries(user, from, to)
+	if err != nil {
+		return err
+	}
+	entries = scraper.ApplyTotals(mode, entries)
+
+	sheets := buildSheets(user.Username, entries)
+	archive := zip.NewWriter(w)
+
+	overrides := ""
+	workbookSheets := ""
+	workbookRels := ""
+	for i, s := range sheets {
+		overrides += fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		workbookSheets += fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, s.name, i+1, i+1)
+		workbookRels += fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	workbookRels += fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+	workbookRels += fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="sharedStrings.xml"/>`, len(sheets)+2)
+
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", fmt.Sprintf(contentTypesXML, overrides)},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + workbookSheets + `</sheets><calcPr fullCalcOnLoad="1"/></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + workbookRels + `</Relationships>`},
+		{"xl/styles.xml", stylesXML},
+	}
+
+	for _, file := range files {
+		if err := writeZipFile(archive, file.name, file.content); err != nil {
+			return err
+		}
+	}
+
+	shared := newSharedStrings()
+	for i, s := range sheets {
+		file, err := archive.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := s.writeXML(file, shared); err != nil {
+			return err
+		}
+	}
+
+	if err := writeZipFile(archive, "xl/sharedStrings.xml", shared.xml()); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
@@ -0,0 +1,214 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+var testUser = scraper.User{Username: "ana", ID: "1"}
+
+var testEntries = []scraper.DiaryEntry{
+	{
+		Date: "12/05/2025", Calories: "1.845", Fat: "60,5", Carbs: "200", Protein: "110",
+		Meals: []scraper.MealData{{
+			Name: "Almoço", Calories: "700", Fat: "20", Carbs: "80", Protein: "45",
+			Items: []scraper.FoodItem{
+				{Name: "Feijão & arroz <caseiro>", FoodID: "42", Quantity: "1 prato", Calories: "450", Fat: "8", Carbs: "70", Protein: "15"},
+				{Name: "Bife", FoodID: "7", Quantity: "120 g", Calories: "250", Fat: "12", Carbs: "10", Protein: "30"},
+			},
+		}},
+	},
+	{Date: "13/05/2025", Calories: "2.100", Fat: "70", Carbs: "250", Protein: "120"},
+}
+
+// writeTestEntries stores entries the way the scraper does, in an output
+// directory under a temporary working directory.
+func writeTestEntries(t *testing.T, user scraper.User, entries []scraper.DiaryEntry) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if err := os.MkdirAll(scraper.OutputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		date, err := time.Parse("02/01/2006", entry.Date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(map[string]any{"user": user, "entry": entry})
+		if err != nil {
+			t.Fatal(err)
+		}
+		filename := filepath.Join(scraper.OutputDir, user.Username+"_"+date.Format("2006-01-02")+".json")
+		if err := os.WriteFile(filename, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+type xlsxCell struct {
+	Ref     string `xml:"r,attr"`
+	Type    string `xml:"t,attr"`
+	Value   string `xml:"v"`
+	Formula string `xml:"f"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readZipFile(t *testing.T, archive *zip.Reader, name string) []byte {
+	t.Helper()
+
+	file, err := archive.Open(name)
+	if err != nil {
+		t.Fatalf("workbook has no %s: %v", name, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// readSheet returns the cells of a worksheet by reference, with shared
+// strings resolved.
+func readSheet(t *testing.T, archive *zip.Reader, name string, shared []string) map[string]xlsxCell {
+	t.Helper()
+
+	var sheet xlsxSheet
+	if err := xml.Unmarshal(readZipFile(t, archive, name), &sheet); err != nil {
+		t.Fatalf("%s is not valid XML: %v", name, err)
+	}
+
+	cells := make(map[string]xlsxCell)
+	for _, row := range sheet.Rows {
+		for _, cell := range row.Cells {
+			if cell.Type == "s" {
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index >= len(shared) {
+					t.Fatalf("%s cell %s has shared string index %q of %d", name, cell.Ref, cell.Value, len(shared))
+				}
+				cell.Value = shared[index]
+			}
+			cells[cell.Ref] = cell
+		}
+	}
+	return cells
+}
+
+func TestWriteXLSX(t *testing.T) {
+	writeTestEntries(t, testUser, testEntries)
+
+	var buf bytes.Buffer
+	from := time.Date(2025, time.May, 12, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.May, 18, 0, 0, 0, 0, time.UTC)
+	if err := WriteXLSX(&buf, testUser, from, to, scraper.TotalsScraped); err != nil {
+		t.Fatalf("WriteXLSX: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("workbook is not a zip file: %v", err)
+	}
+
+	contentTypes := string(readZipFile(t, archive, "[Content_Types].xml"))
+	for _, part := range []string{"/xl/workbook.xml", "/xl/sharedStrings.xml", "/xl/worksheets/sheet4.xml"} {
+		if !strings.Contains(contentTypes, `PartName="`+part+`"`) {
+			t.Errorf("[Content_Types].xml has no override for %s", part)
+		}
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(readZipFile(t, archive, "xl/workbook.xml"), &workbook); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, sheet := range workbook.Sheets {
+		names = append(names, sheet.Name)
+	}
+	if strings.Join(names, ",") != "Daily,Meals,Foods,Weekly" {
+		t.Errorf("sheets = %v", names)
+	}
+
+	var sst struct {
+		Count       int `xml:"count,attr"`
+		UniqueCount int `xml:"uniqueCount,attr"`
+		Items       []struct {
+			Text string `xml:"t"`
+		} `xml:"si"`
+	}
+	if err := xml.Unmarshal(readZipFile(t, archive, "xl/sharedStrings.xml"), &sst); err != nil {
+		t.Fatalf("sharedStrings.xml is not valid XML: %v", err)
+	}
+	shared := []string{}
+	seen := make(map[string]bool)
+	for _, item := range sst.Items {
+		if seen[item.Text] {
+			t.Errorf("shared string %q is stored twice", item.Text)
+		}
+		seen[item.Text] = true
+		shared = append(shared, item.Text)
+	}
+	if sst.UniqueCount != len(shared) || sst.Count < sst.UniqueCount {
+		t.Errorf("sst count=%d uniqueCount=%d for %d strings", sst.Count, sst.UniqueCount, len(shared))
+	}
+
+	daily := readSheet(t, archive, "xl/worksheets/sheet1.xml", shared)
+	for ref, want := range map[string]string{
+		"A1": "username", "B1": "date", "C1": "calories", "L1": "week",
+		"A2": "ana", "B2": "2025-05-12", "C2": "1845", "D2": "60.5", "L2": "2025-05-12",
+		"B3": "2025-05-13", "C3": "2100", "L3": "2025-05-12",
+	} {
+		if got := daily[ref].Value; got != want {
+			t.Errorf("Daily!%s = %q, want %q", ref, got, want)
+		}
+	}
+	if daily["A2"].Type != "s" || daily["C2"].Type != "" {
+		t.Errorf("Daily!A2 type %q and C2 type %q, want text and number", daily["A2"].Type, daily["C2"].Type)
+	}
+
+	foods := readSheet(t, archive, "xl/worksheets/sheet3.xml", shared)
+	if got := foods["D2"].Value; got != "Feijão & arroz <caseiro>" {
+		t.Errorf("Foods!D2 = %q, want the escaped food name back", got)
+	}
+	if got := foods["D3"].Value; got != "Bife" {
+		t.Errorf("Foods!D3 = %q, want Bife", got)
+	}
+
+	weekly := readSheet(t, archive, "xl/worksheets/sheet4.xml", shared)
+	if weekly["A2"].Value != "2025-05-12" || weekly["B2"].Value != "2" || weekly["B2"].Formula != "COUNTIF(Daily!L:L,A2)" {
+		t.Errorf("Weekly row 2 = %+v %+v", weekly["A2"], weekly["B2"])
+	}
+	if got := weekly["C2"].Value; got != "1972.50" {
+		t.Errorf("Weekly!C2 = %q, want the cached average 1972.50", got)
+	}
+}
@@ -1,23 +1,68 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/alissoncorsair/fatsecret-scrapper/analytics"
+	"github.com/alissoncorsair/fatsecret-scrapper/dashboard"
+	"github.com/alissoncorsair/fatsecret-scrapper/export"
 	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
 	_ "github.com/joho/godotenv/autoload"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	//http.HandleFunc("GET /api/scrape", scrapeHandler)
 	http.HandleFunc("GET /api/users", getUsersHandler)
 	http.HandleFunc("POST /api/users", addUserHandler)
 	http.HandleFunc("GET /api/diary", getDiaryHandler)
+	http.HandleFunc("GET /api/diary/stream", diaryStreamHandler)
 	http.HandleFunc("GET /api/diary/{username}/{id}", getDiaryHandler)
+	http.HandleFunc("POST /api/diary/{username}/entries", addDiaryEntryHandler)
+	http.HandleFunc("GET /api/diary/{username}/versions", getDiaryVersionsHandler)
+	http.HandleFunc("GET /api/diary/{username}/diff", getDiaryDiffHandler)
+	http.HandleFunc("GET /api/users/{username}/weight", getWeightHandler)
+	http.HandleFunc("GET /api/users/{username}/recipes", getRecipesHandler)
+	http.HandleFunc("GET /api/users/{username}/foods", getCustomFoodsHandler)
+	http.HandleFunc("GET /api/users/{username}/saved-meals", getSavedMealsHandler)
+	http.HandleFunc("GET /api/users/{username}/fhir", getFHIRHandler)
+	http.HandleFunc("GET /api/users/{username}/stats", getStatsHandler)
+	http.HandleFunc("PUT /api/users/{username}/targets", updateTargetsHandler)
+	http.HandleFunc("PUT /api/users/{username}/privacy", updatePrivacyHandler)
+	http.HandleFunc("GET /api/users/{username}/adherence", getAdherenceHandler)
+	http.HandleFunc("GET /api/users/{username}/consistency", getConsistencyHandler)
+	http.HandleFunc("GET /api/users/{username}/meals", getMealDistributionHandler)
+	http.HandleFunc("GET /api/users/{username}/anomalies", getAnomaliesHandler)
+	http.HandleFunc("GET /api/users/{username}/tdee", getTDEEHandler)
+	http.HandleFunc("GET /api/foods/search", searchFoodsHandler)
+	http.HandleFunc("GET /api/foods/top", getTopFoodsHandler)
+	http.HandleFunc("GET /api/leaderboard", getLeaderboardHandler)
+	http.HandleFunc("GET /api/export", exportHandler)
+	http.HandleFunc("GET /api/webhooks", getWebhooksHandler)
+	http.HandleFunc("POST /api/webhooks", addWebhookHandler)
+	http.HandleFunc("DELETE /api/webhooks/{id}", deleteWebhookHandler)
+	http.HandleFunc("POST /api/webhooks/{id}/ping", pingWebhookHandler)
+	http.HandleFunc("GET /api/webhooks/deliveries", getWebhookDeliveriesHandler)
+	http.HandleFunc("GET /api/scrape/status", getScrapeStatusHandler)
+	http.HandleFunc("GET /dashboard", dashboardHandler)
+	http.HandleFunc("GET /dashboard/users/{username}", dashboardUserHandler)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -60,6 +105,13 @@ func addUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if newUser.Targets != nil {
+		if err := newUser.Targets.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid targets: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
 	users, err := scraper.LoadUsers()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error loading users: %v", err), http.StatusInternalServerError)
@@ -104,6 +156,10 @@ func getDiaryHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("username", username)
 	id := r.PathValue("id")
 	date := r.URL.Query().Get("date")
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
 
 	var user = scraper.User{
 		Username: username,
@@ -111,7 +167,14 @@ func getDiaryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if date == "" {
-		diaries := scraper.ScrapeFatSecret(login, password, []scraper.User{user})
+		diaries, err := scraper.ScrapeFatSecret(login, password, []scraper.User{user})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error scraping diary: %v", err), http.StatusBadGateway)
+			return
+		}
+		for username, entries := range diaries {
+			diaries[username] = scraper.ApplyTotals(mode, entries)
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -126,9 +189,1065 @@ func getDiaryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid date format. Use DD/MM/YYYY", http.StatusBadRequest)
 		return
 	}
-	diaries := scraper.ScrapeFatSecret(login, password, []scraper.User{user}, convertedDate)
+	diaries, err := scraper.ScrapeFatSecret(login, password, []scraper.User{user}, convertedDate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error scraping diary: %v", err), http.StatusBadGateway)
+		return
+	}
+	for username, entries := range diaries {
+		diaries[username] = scraper.ApplyTotals(mode, entries)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(diaries)
 }
+
+func parseTotalsMode(w http.ResponseWriter, r *http.Request) (scraper.TotalsMode, bool) {
+	mode, err := scraper.ParseTotalsMode(r.URL.Query().Get("totals"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return mode, true
+}
+
+func lookupUser(w http.ResponseWriter, r *http.Request) (scraper.User, bool) {
+	user, found, err := scraper.FindUser(r.PathValue("username"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading users: %v", err), http.StatusInternalServerError)
+		return scraper.User{}, false
+	}
+	if !found {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return scraper.User{}, false
+	}
+
+	return user, true
+}
+
+// maxDateRangeDays caps the from/to span of the range handlers. Diaries are
+// read one file per day, so an open-ended range would read every day since
+// year one for each user.
+const maxDateRangeDays = 731
+
+// checkDateRange rejects ranges that end before they start or that span more
+// than maxDateRangeDays.
+func checkDateRange(from, to time.Time) error {
+	if from.After(to) {
+		return fmt.Errorf("from (%s) is after to (%s)", from.Format("02/01/2006"), to.Format("02/01/2006"))
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > maxDateRangeDays {
+		return fmt.Errorf("range covers %d days, at most %d are allowed", days, maxDateRangeDays)
+	}
+	return nil
+}
+
+func parseDateRange(r *http.Request, defaultDays int) (time.Time, time.Time, error) {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -defaultDays+1)
+
+	for key, date := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := r.URL.Query().Get(key); value != "" {
+			parsed, err := time.Parse("02/01/2006", value)
+			if err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("invalid %s date %q, use DD/MM/YYYY", key, value)
+			}
+			*date = parsed
+		}
+	}
+
+	if err := checkDateRange(from, to); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return from, to, nil
+}
+
+func getWeightHandler(w http.ResponseWriter, r *http.Request) {
+	login := os.Getenv("FATSECRET_LOGIN")
+	password := os.Getenv("FATSECRET_PASSWORD")
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if to.Before(from) {
+		http.Error(w, "'from' must not be after 'to'", http.StatusBadRequest)
+		return
+	}
+	if scraper.WeightHistoryMonths(from, to) > scraper.MaxWeightHistoryMonths {
+		http.Error(w, fmt.Sprintf("Range too long, at most %d months", scraper.MaxWeightHistoryMonths), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := scraper.ScrapeWeightHistory(login, password, user, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error scraping weight history: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+func getMemberLibrary(w http.ResponseWriter, r *http.Request) (scraper.MemberLibrary, bool) {
+	login := os.Getenv("FATSECRET_LOGIN")
+	password := os.Getenv("FATSECRET_PASSWORD")
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return scraper.MemberLibrary{}, false
+	}
+
+	library, err := scraper.ScrapeMemberLibrary(login, password, user)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error scraping member library: %v", err), http.StatusBadGateway)
+		return scraper.MemberLibrary{}, false
+	}
+
+	return library, true
+}
+
+func getRecipesHandler(w http.ResponseWriter, r *http.Request) {
+	library, ok := getMemberLibrary(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(library.Recipes)
+}
+
+func getSavedMealsHandler(w http.ResponseWriter, r *http.Request) {
+	library, ok := getMemberLibrary(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(library.SavedMeals)
+}
+
+func getCustomFoodsHandler(w http.ResponseWriter, r *http.Request) {
+	library, ok := getMemberLibrary(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(library.CustomFoods)
+}
+
+func searchFoodsHandler(w http.ResponseWriter, r *http.Request) {
+	login := os.Getenv("FATSECRET_LOGIN")
+	password := os.Getenv("FATSECRET_PASSWORD")
+	query := r.URL.Query().Get("q")
+
+	if query == "" {
+		http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
+		return
+	}
+
+	page := 0
+	if value := r.URL.Query().Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid page", http.StatusBadRequest)
+			return
+		}
+		page = parsed
+	}
+
+	results, err := scraper.SearchFoods(login, password, query, page)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error searching foods: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+func addDiaryEntryHandler(w http.ResponseWriter, r *http.Request) {
+	login := os.Getenv("FATSECRET_LOGIN")
+	password := os.Getenv("FATSECRET_PASSWORD")
+
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	var entry scraper.NewFoodEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if entry.URL == "" || entry.Servings <= 0 {
+		http.Error(w, "Food URL and a positive number of servings are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := scraper.ValidateFoodURL(entry.URL); err != nil {
+		http.Error(w, fmt.Sprintf("Error checking food URL: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := scraper.MealType(entry.Meal); !ok {
+		http.Error(w, "Invalid meal. Use breakfast, lunch, dinner or snacks", http.StatusBadRequest)
+		return
+	}
+
+	if entry.Date != "" {
+		if _, err := time.Parse("02/01/2006", entry.Date); err != nil {
+			http.Error(w, "Invalid date format. Use DD/MM/YYYY", http.StatusBadRequest)
+			return
+		}
+	}
+
+	err := scraper.AddFoodToDiary(login, password, user, entry)
+	if errors.Is(err, scraper.ErrNotLoginAccount) {
+		http.Error(w, fmt.Sprintf("Entries can only be added to the diary of the logged-in account, not %s", user.Username), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error adding food to diary: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+func parseRequiredDate(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	date, err := time.Parse("02/01/2006", r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "Query parameter 'date' is required. Use DD/MM/YYYY", http.StatusBadRequest)
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+func getDiaryVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	date, ok := parseRequiredDate(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := scraper.LoadDiaryVersions(user, date)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading versions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(versions)
+}
+
+func getDiaryDiffHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	date, ok := parseRequiredDate(w, r)
+	if !ok {
+		return
+	}
+
+	versions := [2]int{}
+	for i, key := range []string{"from", "to"} {
+		value := r.URL.Query().Get(key)
+		if value == "" {
+			continue
+		}
+
+		version, err := strconv.Atoi(value)
+		if err != nil || version < 1 {
+			http.Error(w, fmt.Sprintf("Invalid '%s' version", key), http.StatusBadRequest)
+			return
+		}
+		versions[i] = version
+	}
+
+	diff, err := scraper.DiffDiaryVersions(user, date, versions[0], versions[1])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(diff)
+}
+
+func getWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := scraper.LoadWebhooks()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading webhooks: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+func addWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var subscription scraper.WebhookSubscription
+	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := scraper.ValidateWebhookURL(subscription.URL); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid webhook URL: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, event := range subscription.Events {
+		if event != scraper.EventDiaryCreated && event != scraper.EventDiaryUpdated {
+			http.Error(w, fmt.Sprintf("Unknown event %q", event), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The secret is returned here only; listing webhooks leaves it out.
+	subscription, err := scraper.AddWebhook(subscription)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error saving webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscription)
+}
+
+func deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	deleted, err := scraper.DeleteWebhook(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error deleting webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func pingWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	delivery, found, err := scraper.PingWebhook(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading webhooks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+func getWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := scraper.LoadWebhookDeliveries()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading deliveries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+func diaryStreamHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var users []string
+	if value := r.URL.Query().Get("users"); value != "" {
+		users = strings.Split(value, ",")
+	}
+
+	events, unsubscribe := scraper.SubscribeDiaryEvents(users)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				fmt.Printf("Error encoding %s event: %v\n", event.Type, err)
+				continue
+			}
+
+			fmt.Fprintf(w, "id: %s/%s/%d\n", event.Username, event.Date, event.Version)
+			fmt.Fprintf(w, "event: %s\n", event.Type)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+func exportUsers(w http.ResponseWriter, r *http.Request) ([]scraper.User, bool) {
+	username := r.URL.Query().Get("user")
+	if username == "" {
+		users, err := scraper.LoadUsers()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error loading users: %v", err), http.StatusInternalServerError)
+			return nil, false
+		}
+		return users, true
+	}
+
+	user, found, err := scraper.FindUser(username)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading users: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	if !found {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return []scraper.User{user}, true
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" && format != "parquet" {
+		http.Error(w, "Unsupported format. Use csv, xlsx or parquet", http.StatusBadRequest)
+		return
+	}
+
+	level, err := export.ParseLevel(r.URL.Query().Get("level"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	if format == "xlsx" && r.URL.Query().Get("user") == "" {
+		http.Error(w, "Query parameter 'user' is required for xlsx", http.StatusBadRequest)
+		return
+	}
+
+	users, ok := exportUsers(w, r)
+	if !ok {
+		return
+	}
+
+	// Workbooks and archives are built in memory first, so a failure is a 500
+	// rather than a truncated download.
+	if format == "xlsx" {
+		var workbook bytes.Buffer
+		if err := export.WriteXLSX(&workbook, users[0], from, to, mode); err != nil {
+			http.Error(w, fmt.Sprintf("Error exporting XLSX: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		filename := fmt.Sprintf("%s_%s_%s.xlsx", users[0].Username, from.Format("2006-01-02"), to.Format("2006-01-02"))
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.WriteHeader(http.StatusOK)
+		workbook.WriteTo(w)
+		return
+	}
+
+	if format == "parquet" {
+		var zipped bytes.Buffer
+		archive := zip.NewWriter(&zipped)
+		err := export.WriteParquet(func(path string) (io.WriteCloser, error) {
+			file, err := archive.Create(path)
+			if err != nil {
+				return nil, err
+			}
+			return nopCloser{file}, nil
+		}, users, from, to, mode)
+		if err == nil {
+			err = archive.Close()
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error exporting Parquet: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		filename := fmt.Sprintf("diary_parquet_%s_%s.zip", from.Format("2006-01-02"), to.Format("2006-01-02"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.WriteHeader(http.StatusOK)
+		zipped.WriteTo(w)
+		return
+	}
+
+	filename := fmt.Sprintf("diary_%s_%s_%s.csv", level, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	if err := export.WriteCSV(w, level, users, from, to, mode); err != nil {
+		fmt.Printf("Error exporting CSV: %v\n", err)
+	}
+}
+
+func getFHIRHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	if r.URL.Query().Get("variant") == "omh" {
+		points, err := export.OpenMHealthDataPoints(user, from, to, mode)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(points)
+		return
+	}
+
+	bundle, err := export.FHIRBundle(user, from, to, mode)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/fhir+json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bundle)
+}
+
+func getStatsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	period, err := analytics.ParsePeriod(r.URL.Query().Get("period"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := scraper.LoadDiaryEntries(user, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	entries = scraper.ApplyTotals(mode, entries)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(analytics.ComputeStats(user.Username, period, from, to, entries))
+}
+
+func updateTargetsHandler(w http.ResponseWriter, r *http.Request) {
+	var targets scraper.Targets
+	if err := json.NewDecoder(r.Body).Decode(&targets); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := targets.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid targets: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, found, err := scraper.UpdateUserTargets(r.PathValue("username"), targets)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error saving users: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+func getAdherenceHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	period, err := analytics.ParsePeriod(r.URL.Query().Get("period"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := scraper.LoadDiaryEntries(user, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	entries = scraper.ApplyTotals(mode, entries)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(analytics.ComputeAdherence(user, period, from, to, entries))
+}
+
+func getConsistencyHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := scraper.LoadDiaryEntries(user, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	entries = scraper.ApplyTotals(mode, entries)
+
+	report := analytics.ComputeConsistency(user.Username, from, to, entries)
+	if err := analytics.AddLoggedTimes(&report, user); err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+func loadUsersEntries(users []scraper.User, from, to time.Time, mode scraper.TotalsMode) (map[string][]scraper.DiaryEntry, error) {
+	entries := make(map[string][]scraper.DiaryEntry)
+	for _, user := range users {
+		userEntries, err := scraper.LoadDiaryEntries(user, from, to)
+		if err != nil {
+			return nil, err
+		}
+		entries[user.Username] = scraper.ApplyTotals(mode, userEntries)
+	}
+	return entries, nil
+}
+
+// getTopFoodsHandler ranks foods for one user, or across all users when no
+// user is given, and compares them with the previous period of the same
+// length unless compare_from and compare_to pick another one.
+func getTopFoodsHandler(w http.ResponseWriter, r *http.Request) {
+	users, ok := exportUsers(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	compareTo := from.AddDate(0, 0, -1)
+	compareFrom := compareTo.Add(from.Sub(to))
+	for key, date := range map[string]*time.Time{"compare_from": &compareFrom, "compare_to": &compareTo} {
+		if value := r.URL.Query().Get(key); value != "" {
+			if *date, err = time.Parse("02/01/2006", value); err != nil {
+				http.Error(w, "Invalid date format. Use DD/MM/YYYY", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if err := checkDateRange(compareFrom, compareTo); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid comparison range: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 10
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	current, err := loadUsersEntries(users, from, to, mode)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	previous, err := loadUsersEntries(users, compareFrom, compareTo, mode)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(analytics.ComputeFoodReport(from, to, current, compareFrom, compareTo, previous, limit))
+}
+
+func getMealDistributionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	period, err := analytics.ParsePeriod(r.URL.Query().Get("period"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := scraper.LoadDiaryEntries(user, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	entries = scraper.ApplyTotals(mode, entries)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(analytics.ComputeMealDistribution(user.Username, period, from, to, entries))
+}
+
+func updatePrivacyHandler(w http.ResponseWriter, r *http.Request) {
+	var privacy scraper.Privacy
+	if err := json.NewDecoder(r.Body).Decode(&privacy); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, found, err := scraper.UpdateUserPrivacy(r.PathValue("username"), privacy)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error saving users: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+func getLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	metric, err := analytics.ParseLeaderboardMetric(r.URL.Query().Get("metric"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	period, err := analytics.ParsePeriod(r.URL.Query().Get("period"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	date := time.Now().UTC().Truncate(24 * time.Hour)
+	if value := r.URL.Query().Get("date"); value != "" {
+		if date, err = time.Parse("02/01/2006", value); err != nil {
+			http.Error(w, "Invalid date format. Use DD/MM/YYYY", http.StatusBadRequest)
+			return
+		}
+	}
+	from, to := analytics.CurrentPeriod(period, date)
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	users, err := scraper.LoadUsers()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading users: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	entries, err := loadUsersEntries(users, from, to, mode)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(analytics.ComputeLeaderboard(metric, period, from, to, users, entries))
+}
+
+func getAnomaliesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	anomalies, err := scraper.DetectUserAnomalies(user, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(anomalies)
+}
+
+// getTDEEHandler estimates maintenance calories from the stored diary
+// entries and weight history; it doesn't scrape weigh-ins itself.
+func getTDEEHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseDateRange(r, 56)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	window := 28
+	if value := r.URL.Query().Get("window"); value != "" {
+		if window, err = strconv.Atoi(value); err != nil || window < 7 {
+			http.Error(w, "Invalid window, use at least 7 days", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := scraper.LoadDiaryEntries(user, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	entries = scraper.ApplyTotals(mode, entries)
+
+	weights, err := scraper.LoadWeightHistory(user)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading weight history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(analytics.ComputeTDEE(user.Username, from, to, window, entries, weights))
+}
+
+func getScrapeStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scraper.CurrentScrapeStatus())
+}
+
+func writeHTML(w http.ResponseWriter, page *bytes.Buffer) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	page.WriteTo(w)
+}
+
+func dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	users, err := scraper.LoadUsers()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading users: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	entries, err := loadUsersEntries(users, from, to, mode)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var page bytes.Buffer
+	if err := dashboard.RenderIndex(&page, scraper.CurrentScrapeStatus(), users, entries, from, to); err != nil {
+		http.Error(w, fmt.Sprintf("Error rendering dashboard: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeHTML(w, &page)
+}
+
+func dashboardUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := lookupUser(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseDateRange(r, 56)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mode, ok := parseTotalsMode(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := scraper.LoadDiaryEntries(user, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error loading diary entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	entries = scraper.ApplyTotals(mode, entries)
+
+	anomalies, err := scraper.DetectUserAnomalies(user, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error detecting anomalies: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var page bytes.Buffer
+	if err := dashboard.RenderUser(&page, user, entries, anomalies, from, to); err != nil {
+		http.Error(w, fmt.Sprintf("Error rendering dashboard: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeHTML(w, &page)
+}
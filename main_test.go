@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alissoncorsair/fatsecret-scrapper/scraper"
+)
+
+// useTestData runs the test from an empty directory holding users.json with
+// ana and the given stored files under output/, by name.
+func useTestData(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if err := scraper.SaveUsers([]scraper.User{{Username: "ana", ID: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(scraper.OutputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(scraper.OutputDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func storedEntry(t *testing.T, entry scraper.DiaryEntry) string {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"user": scraper.User{Username: "ana", ID: "1"}, "entry": entry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestExportHandlerBinaryFormats(t *testing.T) {
+	entry := storedEntry(t, scraper.DiaryEntry{Date: "12/05/2025", Calories: "1.845"})
+
+	for _, format := range []string{"xlsx", "parquet"} {
+		useTestData(t, map[string]string{"ana_2025-05-12.json": entry})
+
+		target := "/api/export?format=" + format + "&user=ana&from=12/05/2025&to=13/05/2025"
+		recorder := httptest.NewRecorder()
+		exportHandler(recorder, httptest.NewRequest("GET", target, nil))
+
+		if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Body.String(), "PK") {
+			t.Errorf("%s export = %d, want 200 with a zip body", format, recorder.Code)
+		}
+
+		useTestData(t, map[string]string{"ana_2025-05-12.json": entry, "ana_2025-05-13.json": "{"})
+
+		recorder = httptest.NewRecorder()
+		exportHandler(recorder, httptest.NewRequest("GET", target, nil))
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("%s export of an unreadable day = %d, want 500", format, recorder.Code)
+		}
+		if disposition := recorder.Header().Get("Content-Disposition"); disposition != "" {
+			t.Errorf("%s export failed but still sent Content-Disposition %q", format, disposition)
+		}
+	}
+}
+
+func TestDateRangeHandlersRejectBadRanges(t *testing.T) {
+	useTestData(t, nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users/{username}/stats", getStatsHandler)
+	mux.HandleFunc("GET /api/foods/top", getTopFoodsHandler)
+	mux.HandleFunc("GET /api/export", exportHandler)
+
+	for _, target := range []string{
+		"/api/users/ana/stats",
+		"/api/foods/top?user=ana",
+		"/api/export?format=csv&user=ana",
+	} {
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+
+		for query, want := range map[string]int{
+			"from=01/05/2025&to=31/05/2025": http.StatusOK,
+			"from=31/05/2025&to=01/05/2025": http.StatusBadRequest,
+			"from=01/01/0001&to=31/05/2025": http.StatusBadRequest,
+			"from=2025-05-01":               http.StatusBadRequest,
+		} {
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest("GET", target+separator+query, nil))
+			if recorder.Code != want {
+				t.Errorf("%s with %s = %d, want %d", target, query, recorder.Code, want)
+			}
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/foods/top?user=ana&compare_from=01/01/0001&compare_to=30/04/2025", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("top foods compared with an unbounded range = %d, want 400", recorder.Code)
+	}
+}
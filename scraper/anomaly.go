@@ -0,0 +1,296 @@
+package scraper
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// Limits for the rule-based checks. They are deliberately loose: they should
+// catch typos such as 5 kg of rice, not unusual but real days.
+const (
+	maxDayCalories       = 8000
+	minDayCalories       = 500
+	maxItemCalories      = 3000
+	maxItemGrams         = 2000
+	macroEnergyTolerance = 0.25
+
+	// A day is a statistical outlier when it is this many standard deviations
+	// from the user's history, which needs at least zScoreMinDays logged days.
+	zScoreThreshold = 3
+	zScoreMinDays   = 7
+
+	// AnomalyHistoryDays is how far back the z-score baseline looks.
+	AnomalyHistoryDays = 90
+)
+
+const (
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+type Anomaly struct {
+	Date     string  `json:"date"`
+	Rule     string  `json:"rule"`
+	Severity string  `json:"severity"`
+	Message  string  `json:"message"`
+	Meal     string  `json:"meal,omitempty"`
+	Food     string  `json:"food,omitempty"`
+	Value    float64 `json:"value"`
+	Expected float64 `json:"expected,omitempty"`
+	ZScore   float64 `json:"z_score,omitempty"`
+}
+
+var gramsPerUnit = map[string]float64{"g": 1, "ml": 1, "kg": 1000, "l": 1000, "oz": 28.35}
+
+// itemGrams returns the weight or volume of a logged item, or 0 when it was
+// logged in household units.
+func itemGrams(item FoodItem) float64 {
+	return item.ServingAmount * gramsPerUnit[item.ServingUnit]
+}
+
+func checkEntryRules(entry DiaryEntry) []Anomaly {
+	anomalies := []Anomaly{}
+	calories := ParseNumber(entry.Calories)
+
+	add := func(anomaly Anomaly) {
+		anomaly.Date = entry.Date
+		anomalies = append(anomalies, anomaly)
+	}
+
+	if calories > maxDayCalories {
+		add(Anomaly{
+			Rule: "high_calories", Severity: SeverityError, Value: calories, Expected: maxDayCalories,
+			Message: fmt.Sprintf("%.0f kcal logged, more than %d", calories, maxDayCalories),
+		})
+	}
+
+	items := 0
+	for _, meal := range entry.Meals {
+		for _, item := range meal.Items {
+			items++
+
+			if grams := itemGrams(item); grams > maxItemGrams {
+				add(Anomaly{
+					Rule: "large_quantity", Severity: SeverityError, Meal: meal.Name, Food: item.Name,
+					Value: grams, Expected: maxItemGrams,
+					Message: fmt.Sprintf("%s logged as %s", item.Name, item.Quantity),
+				})
+			}
+
+			if value := ParseNumber(item.Calories); value > maxItemCalories {
+				add(Anomaly{
+					Rule: "high_item_calories", Severity: SeverityWarning, Meal: meal.Name, Food: item.Name,
+					Value: value, Expected: maxItemCalories,
+					Message: fmt.Sprintf("%s has %.0f kcal", item.Name, value),
+				})
+			}
+		}
+	}
+
+	// Totals that don't add up come from the reconciliation, recomputed so
+	// entries stored before it existed are checked the same way.
+	for _, discrepancy := range ReconcileTotals(entry).Discrepancies {
+		if discrepancy.Field != "calories" {
+			continue
+		}
+
+		if discrepancy.Meal != "" {
+			add(Anomaly{
+				Rule: "meal_totals_mismatch", Severity: SeverityWarning, Meal: discrepancy.Meal,
+				Value: discrepancy.Scraped, Expected: discrepancy.Computed,
+				Message: fmt.Sprintf("%s shows %.0f kcal but its items add up to %.0f", discrepancy.Meal, discrepancy.Scraped, discrepancy.Computed),
+			})
+			continue
+		}
+
+		add(Anomaly{
+			Rule: "day_totals_mismatch", Severity: SeverityWarning, Value: discrepancy.Scraped, Expected: discrepancy.Computed,
+			Message: fmt.Sprintf("day shows %.0f kcal but its items add up to %.0f", discrepancy.Scraped, discrepancy.Computed),
+		})
+	}
+
+	if items > 0 && calories > 0 && calories < minDayCalories {
+		add(Anomaly{
+			Rule: "low_calories", Severity: SeverityWarning, Value: calories, Expected: minDayCalories,
+			Message: fmt.Sprintf("only %.0f kcal logged, the day may be incomplete", calories),
+		})
+	}
+
+	// Protein and carbs have 4 kcal/g and fat 9 kcal/g; fibre and alcohol
+	// explain small gaps, not large ones.
+	energy := 4*ParseNumber(entry.Protein) + 4*ParseNumber(entry.Carbs) + 9*ParseNumber(entry.Fat)
+	if calories > 0 && energy > 0 && math.Abs(energy-calories) > macroEnergyTolerance*calories {
+		add(Anomaly{
+			Rule: "macro_energy_mismatch", Severity: SeverityWarning, Value: calories, Expected: energy,
+			Message: fmt.Sprintf("macros account for %.0f kcal but %.0f kcal were logged", energy, calories),
+		})
+	}
+
+	return anomalies
+}
+
+// checkZScores compares the day's calories and macros with the user's
+// logged history.
+func checkZScores(entry DiaryEntry, history []DiaryEntry) []Anomaly {
+	anomalies := []Anomaly{}
+
+	metrics := []struct {
+		name  string
+		unit  string
+		value func(DiaryEntry) string
+	}{
+		{"calories", "kcal", func(e DiaryEntry) string { return e.Calories }},
+		{"protein", "g", func(e DiaryEntry) string { return e.Protein }},
+		{"fat", "g", func(e DiaryEntry) string { return e.Fat }},
+		{"carbs", "g", func(e DiaryEntry) string { return e.Carbs }},
+	}
+
+	for _, metric := range metrics {
+		values := []float64{}
+		for _, day := range history {
+			if day.Date != entry.Date && ParseNumber(day.Calories) > 0 {
+				values = append(values, ParseNumber(metric.value(day)))
+			}
+		}
+		if len(values) < zScoreMinDays {
+			continue
+		}
+
+		mean := 0.0
+		for _, value := range values {
+			mean += value
+		}
+		mean /= float64(len(values))
+
+		variance := 0.0
+		for _, value := range values {
+			variance += (value - mean) * (value - mean)
+		}
+		stddev := math.Sqrt(variance / float64(len(values)))
+		if stddev == 0 {
+			continue
+		}
+
+		value := ParseNumber(metric.value(entry))
+		z := (value - mean) / stddev
+		if math.Abs(z) < zScoreThreshold {
+			continue
+		}
+
+		anomalies = append(anomalies, Anomaly{
+			Date:     entry.Date,
+			Rule:     metric.name + "_outlier",
+			Severity: SeverityWarning,
+			Value:    value,
+			Expected: mean,
+			ZScore:   z,
+			Message:  fmt.Sprintf("%s of %.0f %s is %.1f standard deviations from the usual %.0f", metric.name, value, metric.unit, z, mean),
+		})
+	}
+
+	return anomalies
+}
+
+// DetectAnomalies flags likely logging mistakes on a day: rule-based checks
+// on the entry itself plus a z-score against history, the user's other
+// entries. Days with nothing logged are not checked.
+func DetectAnomalies(entry DiaryEntry, history []DiaryEntry) []Anomaly {
+	if ParseNumber(entry.Calories) == 0 {
+		return []Anomaly{}
+	}
+
+	return append(checkEntryRules(entry), checkZScores(entry, history)...)
+}
+
+// detectAnomaliesFrom checks the entries dated from onwards, each against
+// the AnomalyHistoryDays of entries before it. entries must be sorted oldest
+// first.
+func detectAnomaliesFrom(entries []DiaryEntry, from time.Time) []Anomaly {
+	anomalies := []Anomaly{}
+	for i, entry := range entries {
+		date, err := time.Parse("02/01/2006", entry.Date)
+		if err != nil || date.Before(from) {
+			continue
+		}
+
+		history := []DiaryEntry{}
+		for _, previous := range entries[:i] {
+			if previousDate, err := time.Parse("02/01/2006", previous.Date); err == nil && !previousDate.Before(date.AddDate(0, 0, -AnomalyHistoryDays)) {
+				history = append(history, previous)
+			}
+		}
+
+		anomalies = append(anomalies, DetectAnomalies(entry, history)...)
+	}
+
+	return anomalies
+}
+
+// DetectUserAnomalies checks every stored entry of the user between from and
+// to against the AnomalyHistoryDays before it.
+func DetectUserAnomalies(user User, from, to time.Time) ([]Anomaly, error) {
+	entries, err := LoadDiaryEntries(user, from.AddDate(0, 0, -AnomalyHistoryDays), to)
+	if err != nil {
+		return nil, err
+	}
+
+	return detectAnomaliesFrom(entries, from), nil
+}
+
+// checkScrapedAnomalies runs the anomaly checks over the entries scraped for
+// a user, loading the stored history they are compared with once for all of
+// them, and prints what it finds.
+func checkScrapedAnomalies(user User, scraped []DiaryEntry) []Anomaly {
+	byDate := make(map[time.Time]DiaryEntry)
+	var from, to time.Time
+	for _, entry := range scraped {
+		date, err := time.Parse("02/01/2006", entry.Date)
+		if err != nil {
+			continue
+		}
+		byDate[date] = entry
+		if from.IsZero() || date.Before(from) {
+			from = date
+		}
+		if date.After(to) {
+			to = date
+		}
+	}
+	if len(byDate) == 0 {
+		return []Anomaly{}
+	}
+
+	stored, err := LoadDiaryEntries(user, from.AddDate(0, 0, -AnomalyHistoryDays), to)
+	if err != nil {
+		fmt.Printf("Error loading history for %s: %v\n", user.Username, err)
+		return []Anomaly{}
+	}
+	for _, entry := range stored {
+		if date, err := time.Parse("02/01/2006", entry.Date); err == nil {
+			if _, ok := byDate[date]; !ok {
+				byDate[date] = entry
+			}
+		}
+	}
+
+	dates := slices.SortedFunc(maps.Keys(byDate), time.Time.Compare)
+	entries := make([]DiaryEntry, len(dates))
+	for i, date := range dates {
+		entries[i] = byDate[date]
+	}
+
+	anomalies := detectAnomaliesFrom(entries, from)
+	if len(anomalies) == 0 {
+		return anomalies
+	}
+
+	fmt.Printf("\nAnomalies for %s:\n", user.Username)
+	for _, anomaly := range anomalies {
+		fmt.Printf("- %s [%s] %s: %s\n", anomaly.Date, anomaly.Severity, anomaly.Rule, anomaly.Message)
+	}
+
+	return anomalies
+}
@@ -0,0 +1,35 @@
+package scraper
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCheckScrapedAnomalies(t *testing.T) {
+	chdirTemp(t)
+
+	user := User{Username: "ana", ID: "1"}
+	for day := 1; day <= 10; day++ {
+		entry := DiaryEntry{Date: fmt.Sprintf("%02d/05/2025", day), Calories: fmt.Sprint(1900 + 20*day), Fat: "70", Carbs: "230", Protein: "100"}
+		if err := saveUserDataToJSON(user, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scraped := []DiaryEntry{
+		{Date: "11/05/2025", Calories: "2000", Fat: "70", Carbs: "230", Protein: "100"},
+		{Date: "12/05/2025", Calories: "6000", Fat: "70", Carbs: "230", Protein: "100"},
+	}
+	anomalies := checkScrapedAnomalies(user, scraped)
+
+	rules := make(map[string]string)
+	for _, anomaly := range anomalies {
+		if anomaly.Date != "12/05/2025" {
+			t.Errorf("unexpected anomaly on %s: %s", anomaly.Date, anomaly.Message)
+		}
+		rules[anomaly.Rule] = anomaly.Date
+	}
+	if _, ok := rules["calories_outlier"]; !ok {
+		t.Errorf("the 6000 kcal day was not flagged against the stored history, got %v", anomalies)
+	}
+}
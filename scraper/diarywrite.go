@@ -0,0 +1,315 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ErrNotLoginAccount is returned by AddFoodToDiary when the user isn't the
+// account the credentials log in to, the only diary it can write to.
+var ErrNotLoginAccount = errors.New("entries can only be added to the diary of the logged-in account")
+
+// NewFoodEntry describes a food to add to the logged-in account's diary. URL is
+// the food's FatSecret page, as returned by SearchFoods or scraped FoodItems.
+type NewFoodEntry struct {
+	URL       string  `json:"url"`
+	ServingID string  `json:"serving_id"`
+	Servings  float64 `json:"servings"`
+	Meal      string  `json:"meal"`
+	Date      string  `json:"date"`
+}
+
+// mealTypes maps meal slots to the values of FatSecret's meal dropdown.
+var mealTypes = map[MealSlot]string{
+	SlotBreakfast: "1",
+	SlotLunch:     "2",
+	SlotDinner:    "3",
+	SlotSnacks:    "4",
+}
+
+func MealType(meal string) (string, bool) {
+	value, ok := mealTypes[CanonicalMealSlot(meal)]
+	return value, ok
+}
+
+// extractFieldValues adds what a browser would submit for the visible form
+// controls: text inputs as they are and each dropdown's selected option.
+func extractFieldValues(doc *goquery.Document, formData url.Values) {
+	doc.Find("input[type='text']").Each(func(_ int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		value, _ := s.Attr("value")
+		if name != "" {
+			formData.Set(name, value)
+		}
+	})
+
+	doc.Find("select").Each(func(_ int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		if name == "" {
+			return
+		}
+
+		option := s.Find("option[selected]").First()
+		if option.Length() == 0 {
+			option = s.Find("option").First()
+		}
+		value, _ := option.Attr("value")
+		formData.Set(name, value)
+	})
+}
+
+func findFieldName(doc *goquery.Document, selector, suffix string) string {
+	var fieldName string
+
+	doc.Find(selector).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		name, _ := s.Attr("name")
+		if strings.HasSuffix(strings.ToLower(name), strings.ToLower(suffix)) {
+			fieldName = name
+			return false
+		}
+		return true
+	})
+
+	return fieldName
+}
+
+// addToDiaryButton is the server control behind the food page's "Adicionar"
+// button. Its naming container prefix (ctl00$ctl11$...) changes with the page
+// layout, so only the last segment of the postback target is compared.
+const addToDiaryButton = "AddToDiaryButton"
+
+// findAddToDiaryTarget returns the __doPostBack event target of the food
+// page's add to diary button.
+func findAddToDiaryTarget(doc *goquery.Document) string {
+	var target string
+
+	doc.Find("a, button, input").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		script, _ := s.Attr("onclick")
+		if !strings.Contains(script, "__doPostBack") {
+			script, _ = s.Attr("href")
+		}
+		if !strings.Contains(script, "__doPostBack") {
+			return true
+		}
+
+		parts := strings.Split(script, "'")
+		if len(parts) < 2 {
+			return true
+		}
+
+		segments := strings.Split(parts[1], "$")
+		if segments[len(segments)-1] == addToDiaryButton {
+			target = parts[1]
+			return false
+		}
+		return true
+	})
+
+	return target
+}
+
+// resolveFoodURL resolves a food link against baseURL. Only relative links and
+// links to the FatSecret host are accepted, since the page is fetched and a
+// form is posted back to it with the session cookies.
+func resolveFoodURL(href string) (*url.URL, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := url.Parse(href)
+	if err != nil {
+		return nil, fmt.Errorf("invalid food URL: %v", err)
+	}
+
+	resolved := base.ResolveReference(link)
+	if resolved.Scheme != base.Scheme || !strings.EqualFold(resolved.Host, base.Host) {
+		return nil, fmt.Errorf("food URL must be a path or a %s link", base.Host)
+	}
+
+	return resolved, nil
+}
+
+// ValidateFoodURL reports whether href can be used as NewFoodEntry.URL.
+func ValidateFoodURL(href string) error {
+	_, err := resolveFoodURL(href)
+	return err
+}
+
+func extractPostBackError(doc *goquery.Document) string {
+	message := doc.Find("span.error, div.error, span.validator").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		style, _ := s.Attr("style")
+		return !strings.Contains(strings.ReplaceAll(style, " ", ""), "display:none")
+	}).Text()
+
+	return strings.Join(strings.Fields(message), " ")
+}
+
+func addFoodToDiary(client *http.Client, entry NewFoodEntry, date time.Time) error {
+	mealType, ok := MealType(entry.Meal)
+	if !ok {
+		return fmt.Errorf("unknown meal %q", entry.Meal)
+	}
+
+	pageURL, err := resolveFoodURL(entry.URL)
+	if err != nil {
+		return err
+	}
+
+	query := pageURL.Query()
+	query.Set("dt", convertDateToId(date))
+	if entry.ServingID != "" {
+		query.Set("portionid", entry.ServingID)
+	}
+	pageURL.RawQuery = query.Encode()
+
+	fmt.Printf("Opening food page %s...\n", pageURL.String())
+
+	doc, err := fetchDocument(client, pageURL.String())
+	if err != nil {
+		return fmt.Errorf("failed to get food page: %v", err)
+	}
+
+	formData := extractFormData(doc)
+	extractFieldValues(doc, formData)
+
+	amountField := findFieldName(doc, "input[type='text']", "portionamount")
+	mealField := findFieldName(doc, "select", "meal")
+	if amountField == "" || mealField == "" {
+		return fmt.Errorf("food page has no diary form, is the session logged in?")
+	}
+
+	formData.Set(amountField, strings.ReplaceAll(formatNumber(entry.Servings), ".", ","))
+	formData.Set(mealField, mealType)
+
+	if entry.ServingID != "" {
+		if portionField := findFieldName(doc, "select", "portion"); portionField != "" {
+			formData.Set(portionField, entry.ServingID)
+		}
+	}
+
+	target := findAddToDiaryTarget(doc)
+	if target == "" {
+		return fmt.Errorf("could not find the add to diary button")
+	}
+
+	formData.Set("__EVENTTARGET", target)
+	formData.Set("__EVENTARGUMENT", "")
+
+	actionURL := pageURL.String()
+	if action, exists := doc.Find("form").First().Attr("action"); exists && action != "" {
+		if resolved, err := pageURL.Parse(action); err == nil && resolved.Host == pageURL.Host {
+			actionURL = resolved.String()
+		}
+	}
+
+	req, err := createPostBackRequest(actionURL, formData)
+	if err != nil {
+		return fmt.Errorf("failed to create add food request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("add food request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	fmt.Println("Add food response status code:", resp.StatusCode)
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("add food request returned status %d", resp.StatusCode)
+	}
+
+	resultDoc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to parse add food response: %v", err)
+	}
+
+	if message := extractPostBackError(resultDoc); message != "" {
+		return fmt.Errorf("fatsecret rejected the entry: %s", message)
+	}
+
+	return nil
+}
+
+// loggedInMemberID returns the member ID of the logged-in session. The
+// member's own food diary links to its other journals with that ID, the same
+// one users.json holds. Logins are usually email addresses, so they can't be
+// compared with usernames instead.
+func loggedInMemberID(client *http.Client) (string, error) {
+	doc, err := fetchDocument(client, fmt.Sprintf("%s/Diary.aspx?pa=fj", baseURL))
+	if err != nil {
+		return "", fmt.Errorf("failed to get own diary: %v", err)
+	}
+
+	id := ""
+	doc.Find("a[href*='id=']").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		href, _ := s.Attr("href")
+		link, err := url.Parse(href)
+		if err != nil || link.Query().Get("pa") == "" {
+			return true
+		}
+		id = link.Query().Get("id")
+		return id == ""
+	})
+
+	if id == "" {
+		return "", fmt.Errorf("could not find the member ID of the logged-in account")
+	}
+	return id, nil
+}
+
+// AddFoodToDiary logs a food into the diary of the account the credentials
+// belong to and drops user's cached file for that day so the next read
+// re-scrapes it. It returns ErrNotLoginAccount, before posting anything, when
+// user's member ID isn't the logged-in account's.
+func AddFoodToDiary(username, password string, user User, entry NewFoodEntry) error {
+	if entry.URL == "" {
+		return fmt.Errorf("food URL is required")
+	}
+	if err := ValidateFoodURL(entry.URL); err != nil {
+		return err
+	}
+	if entry.Servings <= 0 {
+		return fmt.Errorf("servings must be greater than zero")
+	}
+
+	date := time.Now()
+	if entry.Date != "" {
+		parsed, err := time.Parse("02/01/2006", entry.Date)
+		if err != nil {
+			return fmt.Errorf("invalid date %q, use DD/MM/YYYY", entry.Date)
+		}
+		date = parsed
+	}
+
+	client, err := loginToFatSecret(username, password)
+	if err != nil {
+		return fmt.Errorf("failed to login: %v", err)
+	}
+
+	memberID, err := loggedInMemberID(client)
+	if err != nil {
+		return err
+	}
+	if memberID != user.ID {
+		return ErrNotLoginAccount
+	}
+
+	if err := addFoodToDiary(client, entry, date); err != nil {
+		return err
+	}
+
+	if err := os.Remove(diaryEntryFilename(user, date)); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Error removing cached diary for %s: %v\n", user.Username, err)
+	}
+
+	return nil
+}
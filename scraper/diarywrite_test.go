@@ -0,0 +1,225 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chdirTemp runs the test from an empty directory so output/ and config/
+// files don't touch the working tree.
+func chdirTemp(t *testing.T) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// useFakeSite points the scraper at server for the rest of the test.
+func useFakeSite(t *testing.T, server *httptest.Server) {
+	t.Helper()
+
+	previousURL, previousTransport := baseURL, httpTransport
+	baseURL, httpTransport = server.URL, server.Client().Transport
+	t.Cleanup(func() { baseURL, httpTransport = previousURL, previousTransport })
+}
+
+const fakeLoginPage = `<html><body><form method="post" action="./Auth.aspx?pa=s">
+<input type="hidden" name="__VIEWSTATE" value="login-state" />
+<button class="signIn" onclick="javascript:__doPostBack('ctl00$ctl12$Logincontrol1$LoginButton','')">Entrar</button>
+</form></body></html>`
+
+const fakeFoodPage = `<html><body><form method="post" action="./arroz?portionid=55&amp;dt=%s">
+<input type="hidden" name="__VIEWSTATE" value="food-state" />
+<input type="text" name="ctl00$ctl11$portionamount" value="1" />
+<select name="ctl00$ctl11$portion"><option value="54" selected>100 g</option><option value="55">1 xícara</option></select>
+<select name="ctl00$ctl11$meal"><option value="1" selected>Café da Manhã</option><option value="3">Jantar</option></select>
+<a href="javascript:__doPostBack('ctl00$ctl11$AddToFavouritesButton','')">Adicionar aos favoritos</a>
+<a href="javascript:__doPostBack('ctl00$ctl11$AddToDiaryButton','')">Adicionar</a>
+</form></body></html>`
+
+const fakeOwnDiary = `<html><body>
+<a href="/Default.aspx?pa=m">Minha FatSecret</a>
+<a href="/Diary.aspx?pa=wj&amp;id=1">Diário de peso</a>
+<a href="/Diary.aspx?pa=aj&amp;id=1">Diário de exercícios</a>
+</body></html>`
+
+// fakeFatSecret serves the login and diary write flow for ana@example.com,
+// member 1. Other Diary.aspx pages are served by journal when it is set.
+type fakeFatSecret struct {
+	mu       sync.Mutex
+	postback url.Values
+	query    url.Values
+	journal  http.HandlerFunc
+}
+
+func (f *fakeFatSecret) handler() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /Auth.aspx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, fakeLoginPage)
+	})
+
+	mux.HandleFunc("POST /Auth.aspx", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("__VIEWSTATE") != "login-state" ||
+			r.PostForm.Get("__EVENTTARGET") != "ctl00$ctl12$Logincontrol1$LoginButton" ||
+			r.PostForm.Get("ctl00$ctl12$Logincontrol1$Name") != "ana@example.com" ||
+			r.PostForm.Get("ctl00$ctl12$Logincontrol1$Password") != "secret" {
+			fmt.Fprint(w, fakeLoginPage)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "ana", Path: "/"})
+		http.Redirect(w, r, "/Default.aspx", http.StatusFound)
+	})
+
+	mux.HandleFunc("GET /Default.aspx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body>Bem-vindo</body></html>")
+	})
+
+	mux.HandleFunc("GET /Diary.aspx", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pa") == "fj" && r.URL.Query().Get("id") == "" {
+			if cookie, err := r.Cookie("session"); err == nil && cookie.Value == "ana" {
+				fmt.Fprint(w, fakeOwnDiary)
+				return
+			}
+		}
+		if f.journal == nil {
+			http.NotFound(w, r)
+			return
+		}
+		f.journal(w, r)
+	})
+
+	mux.HandleFunc("/calorias-nutricao/generico/arroz", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "ana" {
+			fmt.Fprint(w, "<html><body>Entre para adicionar ao diário</body></html>")
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			f.mu.Lock()
+			f.query = r.URL.Query()
+			f.mu.Unlock()
+			fmt.Fprintf(w, fakeFoodPage, r.URL.Query().Get("dt"))
+			return
+		}
+
+		r.ParseForm()
+		f.mu.Lock()
+		f.postback = r.PostForm
+		f.mu.Unlock()
+		fmt.Fprint(w, "<html><body>Adicionado</body></html>")
+	})
+
+	return mux
+}
+
+func TestAddFoodToDiary(t *testing.T) {
+	chdirTemp(t)
+
+	fake := &fakeFatSecret{}
+	server := httptest.NewServer(fake.handler())
+	defer server.Close()
+	useFakeSite(t, server)
+
+	user := User{Username: "ana", ID: "1"}
+	date := time.Date(2025, time.May, 15, 0, 0, 0, 0, time.UTC)
+
+	cached := diaryEntryFilename(user, date)
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cached, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := NewFoodEntry{
+		URL:       "/calorias-nutricao/generico/arroz",
+		ServingID: "55",
+		Servings:  1.5,
+		Meal:      "jantar",
+		Date:      "15/05/2025",
+	}
+	if err := AddFoodToDiary("ana@example.com", "secret", user, entry); err != nil {
+		t.Fatalf("AddFoodToDiary: %v", err)
+	}
+
+	if got, want := fake.query.Get("dt"), convertDateToId(date); got != want {
+		t.Errorf("food page dt = %q, want %q", got, want)
+	}
+
+	want := map[string]string{
+		"__VIEWSTATE":               "food-state",
+		"__EVENTTARGET":             "ctl00$ctl11$AddToDiaryButton",
+		"ctl00$ctl11$portionamount": "1,5",
+		"ctl00$ctl11$portion":       "55",
+		"ctl00$ctl11$meal":          "3",
+	}
+	if fake.postback == nil {
+		t.Fatal("add to diary form was never posted")
+	}
+	for field, value := range want {
+		if got := fake.postback.Get(field); got != value {
+			t.Errorf("postback %s = %q, want %q", field, got, value)
+		}
+	}
+
+	if _, err := os.Stat(cached); !os.IsNotExist(err) {
+		t.Errorf("cached diary %s was not removed", cached)
+	}
+}
+
+func TestAddFoodToDiaryRejectsOtherHosts(t *testing.T) {
+	for _, href := range []string{
+		"https://attacker.example/calorias-nutricao/arroz",
+		"//attacker.example/calorias-nutricao/arroz",
+		"http://www.fatsecret.com.br/calorias-nutricao/arroz",
+	} {
+		if err := ValidateFoodURL(href); err == nil {
+			t.Errorf("ValidateFoodURL(%q) accepted a link off the FatSecret site", href)
+		}
+	}
+
+	for _, href := range []string{
+		"/calorias-nutricao/generico/arroz",
+		"calorias-nutricao/generico/arroz",
+		"https://www.fatsecret.com.br/calorias-nutricao/generico/arroz",
+	} {
+		if err := ValidateFoodURL(href); err != nil {
+			t.Errorf("ValidateFoodURL(%q): %v", href, err)
+		}
+	}
+}
+
+func TestAddFoodToDiaryRejectsOtherMembers(t *testing.T) {
+	chdirTemp(t)
+
+	fake := &fakeFatSecret{}
+	server := httptest.NewServer(fake.handler())
+	defer server.Close()
+	useFakeSite(t, server)
+
+	entry := NewFoodEntry{URL: "/calorias-nutricao/generico/arroz", Servings: 1, Meal: "almoço", Date: "15/05/2025"}
+	for _, user := range []User{{Username: "ana@example.com", ID: "2"}, {Username: "ana", ID: ""}} {
+		if err := AddFoodToDiary("ana@example.com", "secret", user, entry); !errors.Is(err, ErrNotLoginAccount) {
+			t.Errorf("AddFoodToDiary for member %q = %v, want ErrNotLoginAccount", user.ID, err)
+		}
+	}
+	if fake.query != nil || fake.postback != nil {
+		t.Error("the food page was opened for another member's diary")
+	}
+}
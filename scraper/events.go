@@ -0,0 +1,104 @@
+package scraper
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	EventDiaryCreated = "diary.created"
+	EventDiaryUpdated = "diary.updated"
+	EventPing         = "ping"
+)
+
+// DiaryEvent is emitted whenever a scrape stores a day that is new or differs
+// from its previous version.
+type DiaryEvent struct {
+	Type       string     `json:"type"`
+	Username   string     `json:"username"`
+	Date       string     `json:"date"`
+	Version    int        `json:"version"`
+	OccurredAt string     `json:"occurred_at"`
+	Entry      DiaryEntry `json:"entry"`
+	Diff       *DiaryDiff `json:"diff,omitempty"`
+}
+
+func newDiaryEvent(user User, previous *DiaryVersion, current DiaryVersion) DiaryEvent {
+	event := DiaryEvent{
+		Type:       EventDiaryCreated,
+		Username:   user.Username,
+		Date:       current.Entry.Date,
+		Version:    current.Version,
+		OccurredAt: time.Now().UTC().Format(time.RFC3339),
+		Entry:      current.Entry,
+	}
+
+	if previous != nil {
+		diff := DiffDiaryEntries(previous.Entry, current.Entry)
+		diff.From = previous.Version
+		diff.To = current.Version
+
+		event.Type = EventDiaryUpdated
+		event.Diff = &diff
+	}
+
+	return event
+}
+
+type eventSubscriber struct {
+	users  []string
+	events chan DiaryEvent
+}
+
+var eventSubscribers = struct {
+	sync.Mutex
+	list []*eventSubscriber
+}{}
+
+// SubscribeDiaryEvents registers a listener for diary events of the given
+// users, or of every user when none are given. The returned function must be
+// called to unsubscribe. Events are dropped for listeners that fall behind.
+func SubscribeDiaryEvents(users []string) (<-chan DiaryEvent, func()) {
+	subscriber := &eventSubscriber{
+		users:  users,
+		events: make(chan DiaryEvent, 16),
+	}
+
+	eventSubscribers.Lock()
+	eventSubscribers.list = append(eventSubscribers.list, subscriber)
+	eventSubscribers.Unlock()
+
+	unsubscribe := func() {
+		eventSubscribers.Lock()
+		eventSubscribers.list = slices.DeleteFunc(eventSubscribers.list, func(s *eventSubscriber) bool {
+			return s == subscriber
+		})
+		eventSubscribers.Unlock()
+	}
+
+	return subscriber.events, unsubscribe
+}
+
+func broadcastDiaryEvent(event DiaryEvent) {
+	eventSubscribers.Lock()
+	defer eventSubscribers.Unlock()
+
+	for _, subscriber := range eventSubscribers.list {
+		if len(subscriber.users) > 0 && !slices.Contains(subscriber.users, event.Username) {
+			continue
+		}
+
+		select {
+		case subscriber.events <- event:
+		default:
+			fmt.Printf("Dropping %s event for %s: stream subscriber is too slow\n", event.Type, event.Username)
+		}
+	}
+}
+
+func publishDiaryEvent(event DiaryEvent) {
+	broadcastDiaryEvent(event)
+	notifyWebhooks(event)
+}
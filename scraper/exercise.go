@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type ExerciseItem struct {
+	Name     string `json:"name"`
+	Duration string `json:"duration"`
+	Calories string `json:"calories"`
+}
+
+type ExerciseEntry struct {
+	Date     string         `json:"date"`
+	Calories string         `json:"calories"`
+	Items    []ExerciseItem `json:"items"`
+}
+
+func extractExerciseItem(tr *goquery.Selection) ExerciseItem {
+	item := ExerciseItem{}
+
+	nameLink := tr.Find("td:nth-child(1) a")
+	if nameLink.Length() > 0 {
+		item.Name = strings.TrimSpace(nameLink.Text())
+	}
+
+	tds := tr.Find("td.normal")
+	if tds.Length() >= 1 {
+		item.Duration = strings.TrimSpace(tds.Eq(0).Text())
+	}
+
+	if tds.Length() >= 2 {
+		item.Calories = strings.TrimSpace(tds.Eq(1).Text())
+	}
+
+	return item
+}
+
+func extractExerciseEntry(doc *goquery.Document) ExerciseEntry {
+	entry := ExerciseEntry{}
+
+	headerTable := doc.Find("div.MyFSHeaderFooterAdditional table.foodsNutritionTbl").First()
+	if headerTable.Length() > 0 {
+		tds := headerTable.Find("td.sub")
+		if tds.Length() > 0 {
+			entry.Calories = strings.TrimSpace(tds.Last().Text())
+		}
+	}
+
+	items := []ExerciseItem{}
+	doc.Find("table.generic.foodsTbl tr td.borderLeft.borderRight").Each(func(_ int, s *goquery.Selection) {
+		itemTr := s.Find("table.foodsNutritionTbl tr")
+		if itemTr.Length() > 0 {
+			item := extractExerciseItem(itemTr)
+			if item.Name != "" {
+				items = append(items, item)
+			}
+		}
+	})
+
+	entry.Items = items
+
+	if entry.Calories == "" && len(items) > 0 {
+		var total float64
+		for _, item := range items {
+			total += ParseNumber(item.Calories)
+		}
+		entry.Calories = formatNumber(total)
+	}
+
+	return entry
+}
+
+func getUserExerciseEntry(client *http.Client, user User, date time.Time) (ExerciseEntry, error) {
+	dateID := convertDateToId(date)
+	exerciseDiaryURL := fmt.Sprintf("%s/Diary.aspx?pa=aj&id=%s&dt=%s", baseURL, user.ID, dateID)
+	fmt.Printf("Accessing exercise journal for %s...\n", user.Username)
+
+	exerciseResp, err := client.Get(exerciseDiaryURL)
+	if err != nil {
+		return ExerciseEntry{}, err
+	}
+	defer exerciseResp.Body.Close()
+
+	exerciseDoc, err := goquery.NewDocumentFromReader(exerciseResp.Body)
+	if err != nil {
+		return ExerciseEntry{}, err
+	}
+
+	entry := extractExerciseEntry(exerciseDoc)
+	entry.Date = date.Format("02/01/2006")
+
+	return entry, nil
+}
+
+func calculateNetCalories(consumed, burned string) string {
+	return formatNumber(ParseNumber(consumed) - ParseNumber(burned))
+}
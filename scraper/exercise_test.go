@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// parseFixture parses a trimmed-down copy of a fatsecret.com.br page.
+func parseFixture(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+const exercisePage = `<html><body>
+<div class="MyFSHeaderFooterAdditional"><table class="foodsNutritionTbl"><tr>
+<td class="sub">Total</td><td class="sub">1h 15min</td><td class="sub">1.120</td>
+</tr></table></div>
+<table class="generic foodsTbl">
+<tr><td class="borderLeft borderRight"><table class="foodsNutritionTbl"><tr>
+<td><a href="/Diary.aspx?pa=ex&amp;e=1">Corrida (10 km/h)</a></td><td class="normal">45 min</td><td class="normal">690</td>
+</tr></table></td></tr>
+<tr><td class="borderLeft borderRight"><table class="foodsNutritionTbl"><tr>
+<td><a href="/Diary.aspx?pa=ex&amp;e=2">Musculação</a></td><td class="normal">30 min</td><td class="normal">430</td>
+</tr></table></td></tr>
+<tr><td class="borderLeft borderRight"><table class="foodsNutritionTbl"><tr>
+<td>Sono</td><td class="normal">8h</td><td class="normal">0</td>
+</tr></table></td></tr>
+</table>
+</body></html>`
+
+func TestExtractExerciseEntry(t *testing.T) {
+	entry := extractExerciseEntry(parseFixture(t, exercisePage))
+
+	if entry.Calories != "1.120" {
+		t.Errorf("Calories = %q, want the header total", entry.Calories)
+	}
+
+	want := []ExerciseItem{
+		{Name: "Corrida (10 km/h)", Duration: "45 min", Calories: "690"},
+		{Name: "Musculação", Duration: "30 min", Calories: "430"},
+	}
+	if len(entry.Items) != len(want) {
+		t.Fatalf("got %d items %+v, want %d", len(entry.Items), entry.Items, len(want))
+	}
+	for i, item := range want {
+		if entry.Items[i] != item {
+			t.Errorf("item %d = %+v, want %+v", i, entry.Items[i], item)
+		}
+	}
+
+	withoutTotal := strings.Replace(exercisePage, "MyFSHeaderFooterAdditional", "other", 1)
+	if got := extractExerciseEntry(parseFixture(t, withoutTotal)).Calories; got != "1120" {
+		t.Errorf("Calories without a header total = %q, want the items summed", got)
+	}
+	if got := calculateNetCalories("1.845", entry.Calories); got != "725" {
+		t.Errorf("calculateNetCalories = %q, want 725", got)
+	}
+}
@@ -0,0 +1,205 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// FoodNutrients is the nutrition panel shown on a food's own page. Values are
+// for the page's default serving (ServingSize), not for the logged quantity.
+type FoodNutrients struct {
+	ServingSize    string            `json:"serving_size"`
+	Calories       string            `json:"calories"`
+	Fat            string            `json:"fat"`
+	SaturatedFat   string            `json:"saturated_fat"`
+	TransFat       string            `json:"trans_fat"`
+	Cholesterol    string            `json:"cholesterol"`
+	Sodium         string            `json:"sodium"`
+	Potassium      string            `json:"potassium"`
+	Carbs          string            `json:"carbs"`
+	Fiber          string            `json:"fiber"`
+	Sugar          string            `json:"sugar"`
+	Protein        string            `json:"protein"`
+	Micronutrients map[string]string `json:"micronutrients,omitempty"`
+}
+
+// foodCacheTTL bounds how long a long-running server keeps a nutrition panel
+// before fetching it again, since FatSecret foods can be edited.
+const foodCacheTTL = time.Hour
+
+type foodCacheEntry struct {
+	once      sync.Once
+	expires   time.Time
+	nutrients FoodNutrients
+	err       error
+}
+
+// foodCache is shared by every scrape in the process so that a food eaten by
+// several users, on several days or in different portions is only fetched
+// once per foodCacheTTL. It is keyed by food ID.
+var foodCache = struct {
+	sync.Mutex
+	entries map[string]*foodCacheEntry
+}{entries: make(map[string]*foodCacheEntry)}
+
+func absoluteURL(href string) string {
+	if strings.HasPrefix(href, "http") {
+		return href
+	}
+	if !strings.HasPrefix(href, "/") {
+		href = "/" + href
+	}
+	return baseURL + href
+}
+
+func setNutrient(nutrients *FoodNutrients, label, value string) {
+	switch strings.ToLower(label) {
+	case "tamanho da porção", "porção":
+		nutrients.ServingSize = value
+	case "energia", "calorias":
+		if nutrients.Calories == "" || strings.Contains(strings.ToLower(value), "kcal") {
+			nutrients.Calories = value
+		}
+	case "gorduras", "gordura", "gorduras totais":
+		nutrients.Fat = value
+	case "gordura saturada", "gorduras saturadas":
+		nutrients.SaturatedFat = value
+	case "gordura trans", "gorduras trans":
+		nutrients.TransFat = value
+	case "colesterol":
+		nutrients.Cholesterol = value
+	case "sódio":
+		nutrients.Sodium = value
+	case "potássio":
+		nutrients.Potassium = value
+	case "carboidratos", "carboidrato":
+		nutrients.Carbs = value
+	case "fibras", "fibra", "fibra alimentar":
+		nutrients.Fiber = value
+	case "açúcar", "açúcares":
+		nutrients.Sugar = value
+	case "proteínas", "proteína":
+		nutrients.Protein = value
+	default:
+		if nutrients.Micronutrients == nil {
+			nutrients.Micronutrients = make(map[string]string)
+		}
+		nutrients.Micronutrients[label] = value
+	}
+}
+
+func extractFoodNutrients(doc *goquery.Document) FoodNutrients {
+	nutrients := FoodNutrients{}
+
+	panel := doc.Find("div.nutrition_facts").First()
+
+	servingSize := panel.Find("div.serving_size_value").First().Text()
+	if servingSize != "" {
+		nutrients.ServingSize = strings.TrimSpace(servingSize)
+	}
+
+	var label string
+	panel.Find("div.nutrient").Each(func(_ int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		switch {
+		case s.HasClass("left"):
+			label = text
+		case s.HasClass("right") && label != "":
+			setNutrient(&nutrients, label, text)
+			label = ""
+		}
+	})
+
+	return nutrients
+}
+
+func fetchFoodNutrients(client *http.Client, foodURL string) (FoodNutrients, error) {
+	resp, err := client.Get(foodURL)
+	if err != nil {
+		return FoodNutrients{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return FoodNutrients{}, fmt.Errorf("unexpected status %d for %s", resp.StatusCode, foodURL)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return FoodNutrients{}, err
+	}
+
+	return extractFoodNutrients(doc), nil
+}
+
+// foodPageURL drops the portion parameters from a food link. The nutrition
+// panel is for the default serving either way.
+func foodPageURL(foodURL string) string {
+	parsed, err := url.Parse(foodURL)
+	if err != nil {
+		return foodURL
+	}
+
+	query := parsed.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "portion") {
+			query.Del(key)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+func getFoodNutrients(client *http.Client, item FoodItem) (FoodNutrients, error) {
+	key := item.FoodID
+	if key == "" {
+		key = foodPageURL(item.URL)
+	}
+
+	foodCache.Lock()
+	entry, ok := foodCache.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		entry = &foodCacheEntry{expires: time.Now().Add(foodCacheTTL)}
+		foodCache.entries[key] = entry
+	}
+	foodCache.Unlock()
+
+	entry.once.Do(func() {
+		entry.nutrients, entry.err = fetchFoodNutrients(client, foodPageURL(item.URL))
+	})
+
+	if entry.err != nil {
+		foodCache.Lock()
+		if foodCache.entries[key] == entry {
+			delete(foodCache.entries, key)
+		}
+		foodCache.Unlock()
+	}
+
+	return entry.nutrients, entry.err
+}
+
+func enrichFoodItems(client *http.Client, meals []MealData) {
+	for i := range meals {
+		for j := range meals[i].Items {
+			item := &meals[i].Items[j]
+			if item.URL == "" {
+				continue
+			}
+
+			nutrients, err := getFoodNutrients(client, *item)
+			if err != nil {
+				fmt.Printf("Error fetching nutrients for %s: %v\n", item.Name, err)
+				continue
+			}
+			item.Nutrients = &nutrients
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const foodPage = `<html><body><div class="nutrition_facts">
+<div class="serving_size_value">1 xícara (158 g)</div>
+<div class="nutrient left">Energia</div><div class="nutrient right">857 kj</div>
+<div class="nutrient left">Energia</div><div class="nutrient right">205 kcal</div>
+<div class="nutrient left">Gorduras</div><div class="nutrient right">0,44g</div>
+<div class="nutrient left">Gordura Saturada</div><div class="nutrient right">0,12g</div>
+<div class="nutrient left">Carboidratos</div><div class="nutrient right">44,51g</div>
+<div class="nutrient left">Fibra</div><div class="nutrient right">0,6g</div>
+<div class="nutrient left">Proteínas</div><div class="nutrient right">4,25g</div>
+<div class="nutrient left">Sódio</div><div class="nutrient right">2mg</div>
+<div class="nutrient left">Ferro</div><div class="nutrient right">1,9mg</div>
+<div class="nutrient right">sem rótulo</div>
+</div></body></html>`
+
+func TestExtractFoodNutrients(t *testing.T) {
+	nutrients := extractFoodNutrients(parseFixture(t, foodPage))
+
+	want := FoodNutrients{
+		ServingSize:  "1 xícara (158 g)",
+		Calories:     "205 kcal",
+		Fat:          "0,44g",
+		SaturatedFat: "0,12g",
+		Carbs:        "44,51g",
+		Fiber:        "0,6g",
+		Protein:      "4,25g",
+		Sodium:       "2mg",
+	}
+	want.Micronutrients = map[string]string{"Ferro": "1,9mg"}
+	if !reflect.DeepEqual(nutrients, want) {
+		t.Errorf("nutrients = %+v, want %+v", nutrients, want)
+	}
+}
+
+func TestFoodCacheExpires(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		fmt.Fprint(w, foodPage)
+	}))
+	defer server.Close()
+	useFakeSite(t, server)
+
+	item := FoodItem{Name: "Arroz", FoodID: "food-cache-test", URL: server.URL + "/calorias-nutricao/generico/arroz?portionid=55"}
+	t.Cleanup(func() {
+		foodCache.Lock()
+		delete(foodCache.entries, item.FoodID)
+		foodCache.Unlock()
+	})
+
+	for range 2 {
+		if _, err := getFoodNutrients(server.Client(), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Fatalf("fetched %d times within the TTL, want 1", fetches.Load())
+	}
+
+	foodCache.Lock()
+	foodCache.entries[item.FoodID].expires = time.Now().Add(-time.Second)
+	foodCache.Unlock()
+
+	nutrients, err := getFoodNutrients(server.Client(), item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetches.Load() != 2 || nutrients.Calories != "205 kcal" {
+		t.Errorf("after expiry: %d fetches, calories %q", fetches.Load(), nutrients.Calories)
+	}
+}
@@ -0,0 +1,409 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const HistoryDir = "history"
+
+type DiaryVersion struct {
+	Version   int        `json:"version"`
+	ScrapedAt string     `json:"scraped_at"`
+	Entry     DiaryEntry `json:"entry"`
+}
+
+type FoodChange struct {
+	Meal             string  `json:"meal"`
+	Name             string  `json:"name"`
+	Quantity         string  `json:"quantity,omitempty"`
+	PreviousQuantity string  `json:"previous_quantity,omitempty"`
+	CaloriesDelta    float64 `json:"calories_delta"`
+}
+
+type TotalsDelta struct {
+	Calories float64 `json:"calories"`
+	Fat      float64 `json:"fat"`
+	Carbs    float64 `json:"carbs"`
+	Protein  float64 `json:"protein"`
+}
+
+type DiaryDiff struct {
+	Date    string       `json:"date"`
+	From    int          `json:"from"`
+	To      int          `json:"to"`
+	Added   []FoodChange `json:"added"`
+	Removed []FoodChange `json:"removed"`
+	Changed []FoodChange `json:"changed"`
+	Totals  TotalsDelta  `json:"totals"`
+}
+
+func diaryHistoryDir(user User, date time.Time) string {
+	return filepath.Join(OutputDir, HistoryDir, fmt.Sprintf("%s_%s", user.Username, date.Format("2006-01-02")))
+}
+
+// LoadDiaryVersions returns every stored scrape of a day, oldest first.
+func LoadDiaryVersions(user User, date time.Time) ([]DiaryVersion, error) {
+	dir := diaryHistoryDir(user, date)
+
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []DiaryVersion{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history for %s: %v", user.Username, err)
+	}
+
+	versions := []DiaryVersion{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read version %s: %v", file.Name(), err)
+		}
+
+		var version DiaryVersion
+		if err := json.Unmarshal(data, &version); err != nil {
+			return nil, fmt.Errorf("failed to parse version %s: %v", file.Name(), err)
+		}
+		versions = append(versions, version)
+	}
+
+	slices.SortFunc(versions, func(a, b DiaryVersion) int {
+		return a.Version - b.Version
+	})
+
+	return versions, nil
+}
+
+// diaryContent keeps only what the diary page itself shows: the date, the
+// totals and each meal's items with their macros. Nutrient panels, library
+// links, the summary, exercise and reconciliation come from best-effort
+// fetches or are derived, so one failing doesn't make the diary a new version.
+func diaryContent(entry DiaryEntry) DiaryEntry {
+	content := DiaryEntry{
+		Date:     entry.Date,
+		Calories: entry.Calories,
+		IDR:      entry.IDR,
+		Fat:      entry.Fat,
+		Protein:  entry.Protein,
+		Carbs:    entry.Carbs,
+		Meals:    make([]MealData, len(entry.Meals)),
+	}
+
+	for i, meal := range entry.Meals {
+		items := make([]FoodItem, len(meal.Items))
+		for j, item := range meal.Items {
+			items[j] = FoodItem{
+				Name:     item.Name,
+				Quantity: item.Quantity,
+				Fat:      item.Fat,
+				Carbs:    item.Carbs,
+				Protein:  item.Protein,
+				Calories: item.Calories,
+			}
+		}
+		meal.Items = items
+		content.Meals[i] = meal
+	}
+
+	return content
+}
+
+func sameDiaryContent(a, b DiaryEntry) bool {
+	aJSON, errA := json.Marshal(diaryContent(a))
+	bJSON, errB := json.Marshal(diaryContent(b))
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	return bytes.Equal(aJSON, bJSON)
+}
+
+// historyLocks serializes recordDiaryVersion per history directory so that
+// concurrent scrapes of the same day don't both write the same version.
+var historyLocks = struct {
+	sync.Mutex
+	dirs map[string]*sync.Mutex
+}{dirs: make(map[string]*sync.Mutex)}
+
+func lockHistoryDir(dir string) func() {
+	historyLocks.Lock()
+	lock, ok := historyLocks.dirs[dir]
+	if !ok {
+		lock = &sync.Mutex{}
+		historyLocks.dirs[dir] = lock
+	}
+	historyLocks.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// createDiaryVersion writes version to its own file in dir. It reports false,
+// without an error, when another writer already created that version.
+func createDiaryVersion(user User, dir string, version DiaryVersion) (bool, error) {
+	jsonData, err := json.MarshalIndent(version, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal version for %s: %v", user.Username, err)
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("v%04d.json", version.Version))
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create version for %s: %v", user.Username, err)
+	}
+
+	_, err = file.Write(jsonData)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filename)
+		return false, fmt.Errorf("failed to write version for %s: %v", user.Username, err)
+	}
+
+	return true, nil
+}
+
+// seedDiaryHistory stores a day that was cached before its history was
+// recorded as version 1, dated when it was cached. The next scrape is then
+// compared with it, instead of publishing diary.created for a day that
+// already existed. Days that have versions are left alone.
+func seedDiaryHistory(user User, date time.Time, entry DiaryEntry, cachedAt time.Time) error {
+	dir := diaryHistoryDir(user, date)
+	defer lockHistoryDir(dir)()
+
+	versions, err := LoadDiaryVersions(user, date)
+	if err != nil || len(versions) > 0 {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	_, err = createDiaryVersion(user, dir, DiaryVersion{
+		Version:   1,
+		ScrapedAt: cachedAt.UTC().Format(time.RFC3339),
+		Entry:     entry,
+	})
+	return err
+}
+
+// recordDiaryVersion stores the entry as a new version unless it matches the
+// latest one. It returns the previous version, if any, the current one and
+// whether a new version was written. Version files are created exclusively,
+// so another process writing the same day makes it retry with the next
+// number instead of overwriting.
+func recordDiaryVersion(user User, date time.Time, entry DiaryEntry) (*DiaryVersion, DiaryVersion, bool, error) {
+	dir := diaryHistoryDir(user, date)
+	defer lockHistoryDir(dir)()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, DiaryVersion{}, false, fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		versions, err := LoadDiaryVersions(user, date)
+		if err != nil {
+			return nil, DiaryVersion{}, false, err
+		}
+
+		var previous *DiaryVersion
+		number := 1
+		if len(versions) > 0 {
+			latest := versions[len(versions)-1]
+			if sameDiaryContent(latest.Entry, entry) {
+				return nil, latest, false, nil
+			}
+			previous = &latest
+			number = latest.Version + 1
+		}
+
+		version := DiaryVersion{
+			Version:   number,
+			ScrapedAt: time.Now().UTC().Format(time.RFC3339),
+			Entry:     entry,
+		}
+
+		created, err := createDiaryVersion(user, dir, version)
+		if err != nil {
+			return nil, DiaryVersion{}, false, err
+		}
+		if !created {
+			continue
+		}
+
+		fmt.Printf("Recorded version %d of %s's diary for %s\n", version.Version, user.Username, entry.Date)
+		return previous, version, true, nil
+	}
+
+	return nil, DiaryVersion{}, false, fmt.Errorf("failed to record version for %s: history for %s keeps changing", user.Username, entry.Date)
+}
+
+// foodKey identifies a logged food within a meal. Identical items (same food
+// and quantity logged twice) are told apart by occurrence.
+type foodKey struct {
+	meal       string
+	food       string
+	quantity   string
+	occurrence int
+}
+
+func indexFoods(entry DiaryEntry) (map[foodKey]FoodItem, []foodKey) {
+	items := make(map[foodKey]FoodItem)
+	keys := []foodKey{}
+
+	for _, meal := range entry.Meals {
+		seen := make(map[foodKey]int)
+		for _, item := range meal.Items {
+			food := item.FoodID
+			if food == "" {
+				food = strings.ToLower(item.Name)
+			}
+
+			key := foodKey{meal: meal.Name, food: food, quantity: item.Quantity}
+			key.occurrence = seen[key]
+			seen[key]++
+
+			items[key] = item
+			keys = append(keys, key)
+		}
+	}
+
+	return items, keys
+}
+
+// DiffDiaryEntries compares two scrapes of the same day. Foods are matched per
+// meal by FatSecret food ID, falling back to the name for older entries, and
+// by quantity, so removing one item doesn't shift the ones after it. Foods
+// left over on both sides with the same ID are reported as changed.
+func DiffDiaryEntries(from, to DiaryEntry) DiaryDiff {
+	diff := DiaryDiff{
+		Date:    to.Date,
+		Added:   []FoodChange{},
+		Removed: []FoodChange{},
+		Changed: []FoodChange{},
+		Totals: TotalsDelta{
+			Calories: ParseNumber(to.Calories) - ParseNumber(from.Calories),
+			Fat:      ParseNumber(to.Fat) - ParseNumber(from.Fat),
+			Carbs:    ParseNumber(to.Carbs) - ParseNumber(from.Carbs),
+			Protein:  ParseNumber(to.Protein) - ParseNumber(from.Protein),
+		},
+	}
+
+	fromItems, fromKeys := indexFoods(from)
+	toItems, toKeys := indexFoods(to)
+
+	unmatched := []foodKey{}
+	for _, key := range fromKeys {
+		if _, exists := toItems[key]; !exists {
+			unmatched = append(unmatched, key)
+		}
+	}
+
+	for _, key := range toKeys {
+		item := toItems[key]
+
+		if previous, existed := fromItems[key]; existed {
+			if previous.Calories != item.Calories {
+				diff.Changed = append(diff.Changed, FoodChange{
+					Meal:             key.meal,
+					Name:             item.Name,
+					Quantity:         item.Quantity,
+					PreviousQuantity: previous.Quantity,
+					CaloriesDelta:    ParseNumber(item.Calories) - ParseNumber(previous.Calories),
+				})
+			}
+			continue
+		}
+
+		index := slices.IndexFunc(unmatched, func(old foodKey) bool {
+			return old.meal == key.meal && old.food == key.food
+		})
+		if index < 0 {
+			diff.Added = append(diff.Added, FoodChange{
+				Meal:          key.meal,
+				Name:          item.Name,
+				Quantity:      item.Quantity,
+				CaloriesDelta: ParseNumber(item.Calories),
+			})
+			continue
+		}
+
+		previous := fromItems[unmatched[index]]
+		unmatched = slices.Delete(unmatched, index, index+1)
+		diff.Changed = append(diff.Changed, FoodChange{
+			Meal:             key.meal,
+			Name:             item.Name,
+			Quantity:         item.Quantity,
+			PreviousQuantity: previous.Quantity,
+			CaloriesDelta:    ParseNumber(item.Calories) - ParseNumber(previous.Calories),
+		})
+	}
+
+	for _, key := range unmatched {
+		item := fromItems[key]
+		diff.Removed = append(diff.Removed, FoodChange{
+			Meal:          key.meal,
+			Name:          item.Name,
+			Quantity:      item.Quantity,
+			CaloriesDelta: -ParseNumber(item.Calories),
+		})
+	}
+
+	return diff
+}
+
+// DiffDiaryVersions diffs two stored versions of a day. A zero "to" means the
+// latest version and a zero "from" the one before it.
+func DiffDiaryVersions(user User, date time.Time, from, to int) (DiaryDiff, error) {
+	versions, err := LoadDiaryVersions(user, date)
+	if err != nil {
+		return DiaryDiff{}, err
+	}
+	if len(versions) == 0 {
+		return DiaryDiff{}, fmt.Errorf("no versions stored for %s on %s", user.Username, date.Format("02/01/2006"))
+	}
+
+	if to == 0 {
+		to = versions[len(versions)-1].Version
+	}
+	if from == 0 {
+		from = max(to-1, 1)
+	}
+
+	var fromVersion, toVersion *DiaryVersion
+	for i := range versions {
+		if versions[i].Version == from {
+			fromVersion = &versions[i]
+		}
+		if versions[i].Version == to {
+			toVersion = &versions[i]
+		}
+	}
+
+	if fromVersion == nil || toVersion == nil {
+		return DiaryDiff{}, fmt.Errorf("version not found, %s has %d versions for %s", user.Username, len(versions), date.Format("02/01/2006"))
+	}
+
+	diff := DiffDiaryEntries(fromVersion.Entry, toVersion.Entry)
+	diff.From = from
+	diff.To = to
+
+	return diff, nil
+}
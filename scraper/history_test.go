@@ -0,0 +1,157 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecordDiaryVersionConcurrent(t *testing.T) {
+	chdirTemp(t)
+
+	user := User{Username: "ana", ID: "1"}
+	date := time.Date(2025, time.May, 15, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			entry := DiaryEntry{Date: "15/05/2025", Calories: fmt.Sprint(1000 + i)}
+			if _, _, _, err := recordDiaryVersion(user, date, entry); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	versions, err := LoadDiaryVersions(user, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 8 {
+		t.Fatalf("got %d versions, want 8", len(versions))
+	}
+	for i, version := range versions {
+		if version.Version != i+1 {
+			t.Errorf("version %d is numbered %d", i+1, version.Version)
+		}
+	}
+}
+
+func TestDiffDiaryEntriesRemovedItem(t *testing.T) {
+	meal := func(items ...FoodItem) DiaryEntry {
+		return DiaryEntry{Meals: []MealData{{Name: "Almoço", Items: items}}}
+	}
+	rice := FoodItem{Name: "Arroz", FoodID: "1", Quantity: "100 g", Calories: "130"}
+	beans := FoodItem{Name: "Feijão", FoodID: "2", Quantity: "80 g", Calories: "60"}
+	moreRice := FoodItem{Name: "Arroz", FoodID: "1", Quantity: "50 g", Calories: "65"}
+	steak := FoodItem{Name: "Bife", FoodID: "3", Quantity: "120 g", Calories: "250"}
+
+	diff := DiffDiaryEntries(meal(rice, beans, moreRice, steak), meal(beans, moreRice, steak))
+
+	if len(diff.Changed) != 0 || len(diff.Added) != 0 {
+		t.Errorf("removing one item changed %v and added %v", diff.Changed, diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Quantity != "100 g" {
+		t.Errorf("removed = %v, want the 100 g of rice", diff.Removed)
+	}
+
+	moreRice.Quantity, moreRice.Calories = "75 g", "98"
+	diff = DiffDiaryEntries(meal(beans, steak, rice), meal(beans, steak, moreRice))
+
+	if len(diff.Changed) != 1 || diff.Changed[0].PreviousQuantity != "100 g" || diff.Changed[0].Quantity != "75 g" {
+		t.Errorf("changed = %v, want rice from 100 g to 75 g", diff.Changed)
+	}
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("a quantity change added %v and removed %v", diff.Added, diff.Removed)
+	}
+}
+
+func TestSameDiaryContentIgnoresEnrichment(t *testing.T) {
+	entry := DiaryEntry{Date: "15/05/2025", Calories: "130", Meals: []MealData{{
+		Name: "Almoço", Calories: "130",
+		Items: []FoodItem{{Name: "Arroz", Quantity: "100 g", Calories: "130"}},
+	}}}
+
+	enriched := entry
+	enriched.Timestamp = "16/05/2025"
+	enriched.Summary = &DailySummary{}
+	enriched.Exercise = &ExerciseEntry{Calories: "300"}
+	enriched.NetCalories = "-170"
+	reconciliation := ReconcileTotals(entry)
+	enriched.Reconciliation = &reconciliation
+	enriched.Meals = []MealData{entry.Meals[0]}
+	enriched.Meals[0].Items = []FoodItem{entry.Meals[0].Items[0]}
+	enriched.Meals[0].Items[0].Nutrients = &FoodNutrients{Fiber: "0,4g"}
+	enriched.Meals[0].Items[0].RecipeURL = "https://www.fatsecret.com.br/receitas/arroz"
+
+	if !sameDiaryContent(entry, enriched) {
+		t.Error("enrichment alone made a new version")
+	}
+
+	eaten := enriched
+	eaten.Meals = []MealData{enriched.Meals[0]}
+	eaten.Meals[0].Items = []FoodItem{{Name: "Arroz", Quantity: "150 g", Calories: "195"}}
+	if sameDiaryContent(entry, eaten) {
+		t.Error("a changed quantity was not a new version")
+	}
+}
+
+func TestSaveSeedsHistoryFromCachedDay(t *testing.T) {
+	chdirTemp(t)
+
+	events, unsubscribe := SubscribeDiaryEvents([]string{"ana"})
+	defer unsubscribe()
+
+	user := User{Username: "ana", ID: "1"}
+	date := time.Date(2025, time.May, 15, 0, 0, 0, 0, time.UTC)
+	entry := DiaryEntry{Date: "15/05/2025", Calories: "130", Meals: []MealData{{
+		Name: "Almoço", Items: []FoodItem{{Name: "Arroz", Quantity: "100 g", Calories: "130"}},
+	}}}
+
+	// A day cached before history was recorded.
+	if err := os.MkdirAll(OutputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(map[string]any{"user": user, "entry": entry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(diaryEntryFilename(user, date), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveUserDataToJSON(user, entry); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("rescraping an unchanged cached day published %s", event.Type)
+	default:
+	}
+
+	versions, err := LoadDiaryVersions(user, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("got %d versions, want the seeded one", len(versions))
+	}
+
+	entry.Meals[0].Items[0].Quantity, entry.Meals[0].Items[0].Calories = "150 g", "195"
+	if err := saveUserDataToJSON(user, entry); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if event.Type != EventDiaryUpdated || event.Version != 2 {
+			t.Errorf("got %s for version %d, want %s for version 2", event.Type, event.Version, EventDiaryUpdated)
+		}
+	default:
+		t.Error("changing the cached day published nothing")
+	}
+}
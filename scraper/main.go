@@ -18,12 +18,22 @@ import (
 )
 
 type FoodItem struct {
-	Name     string `json:"name"`
-	Quantity string `json:"quantity"`
-	Fat      string `json:"fat"`
-	Carbs    string `json:"carbs"`
-	Protein  string `json:"protein"`
-	Calories string `json:"calories"`
+	Name          string         `json:"name"`
+	Quantity      string         `json:"quantity"`
+	Fat           string         `json:"fat"`
+	Carbs         string         `json:"carbs"`
+	Protein       string         `json:"protein"`
+	Calories      string         `json:"calories"`
+	URL           string         `json:"url,omitempty"`
+	FoodID        string         `json:"food_id,omitempty"`
+	Brand         string         `json:"brand,omitempty"`
+	ServingAmount float64        `json:"serving_amount,omitempty"`
+	ServingUnit   string         `json:"serving_unit,omitempty"`
+	Servings      float64        `json:"servings,omitempty"`
+	Nutrients     *FoodNutrients `json:"nutrients,omitempty"`
+	RecipeURL     string         `json:"recipe_url,omitempty"`
+	SavedMealURL  string         `json:"saved_meal_url,omitempty"`
+	CustomFoodURL string         `json:"custom_food_url,omitempty"`
 }
 
 type MealData struct {
@@ -36,29 +46,44 @@ type MealData struct {
 }
 
 type DiaryEntry struct {
-	Date      string     `json:"date"`
-	Calories  string     `json:"calories"`
-	IDR       string     `json:"idr"`
-	Fat       string     `json:"fat"`
-	Protein   string     `json:"protein"`
-	Carbs     string     `json:"carbs"`
-	Timestamp string     `json:"timestamp"`
-	Meals     []MealData `json:"meals"`
+	Date           string          `json:"date"`
+	Calories       string          `json:"calories"`
+	IDR            string          `json:"idr"`
+	Fat            string          `json:"fat"`
+	Protein        string          `json:"protein"`
+	Carbs          string          `json:"carbs"`
+	Timestamp      string          `json:"timestamp"`
+	Meals          []MealData      `json:"meals"`
+	Summary        *DailySummary   `json:"summary,omitempty"`
+	Exercise       *ExerciseEntry  `json:"exercise,omitempty"`
+	NetCalories    string          `json:"net_calories,omitempty"`
+	Reconciliation *Reconciliation `json:"reconciliation,omitempty"`
 }
 
 type User struct {
-	Username string `json:"username"`
-	ID       string `json:"id"`
+	Username string   `json:"username"`
+	ID       string   `json:"id"`
+	Targets  *Targets `json:"targets,omitempty"`
+	Privacy  *Privacy `json:"privacy,omitempty"`
 }
 
 const (
-	baseURL         = "https://www.fatsecret.com.br"
-	loginPageURL    = "https://www.fatsecret.com.br/Auth.aspx?pa=s"
 	OutputDir       = "output"
 	ConfigDir       = "config"
 	UsersConfigFile = "users.json"
 )
 
+// baseURL and httpTransport are variables so tests can point the scraper at a
+// fake server.
+var (
+	baseURL       = "https://www.fatsecret.com.br"
+	httpTransport = http.DefaultTransport
+)
+
+func loginPageURL() string {
+	return baseURL + "/Auth.aspx?pa=s"
+}
+
 func convertDateToId(date time.Time) string {
 	//March 26, 2025 = 20173
 	//get the diff in days between march 26, 2006 and the date
@@ -107,6 +132,21 @@ func LoadUsers() ([]User, error) {
 	return users, nil
 }
 
+func FindUser(username string) (User, bool, error) {
+	users, err := LoadUsers()
+	if err != nil {
+		return User{}, false, err
+	}
+
+	for _, user := range users {
+		if user.Username == username {
+			return user, true, nil
+		}
+	}
+
+	return User{}, false, nil
+}
+
 func SaveUsers(users []User) error {
 	configPath := filepath.Join(ConfigDir, UsersConfigFile)
 
@@ -129,6 +169,31 @@ func SaveUsers(users []User) error {
 	return nil
 }
 
+// updateUser applies update to the named user and saves users.json. It
+// reports false when there is no such user.
+func updateUser(username string, update func(*User)) (User, bool, error) {
+	users, err := LoadUsers()
+	if err != nil {
+		return User{}, false, err
+	}
+
+	for i := range users {
+		if users[i].Username == username {
+			update(&users[i])
+			if err := SaveUsers(users); err != nil {
+				return User{}, false, err
+			}
+			return users[i], true, nil
+		}
+	}
+
+	return User{}, false, nil
+}
+
+func diaryEntryFilename(user User, date time.Time) string {
+	return filepath.Join(OutputDir, fmt.Sprintf("%s_%s.json", user.Username, date.Format("2006-01-02")))
+}
+
 func saveUserDataToJSON(user User, entry DiaryEntry) error {
 	if err := os.MkdirAll(OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %v", err)
@@ -150,16 +215,37 @@ func saveUserDataToJSON(user User, entry DiaryEntry) error {
 	}
 
 	entryDate, _ := time.Parse("02/01/2006", entry.Date)
-	entryDateStr := entryDate.Format("2006-01-02")
-	filename := filepath.Join(OutputDir, fmt.Sprintf("%s_%s.json",
-		user.Username,
-		entryDateStr))
+	filename := diaryEntryFilename(user, entryDate)
+
+	// Days cached before history was kept become their first version, so
+	// upgrading doesn't announce every one of them as diary.created.
+	if cached, err := loadDiaryEntryFile(filename); err != nil {
+		fmt.Println(err)
+	} else if cached != nil && cached.Date != "" {
+		info, err := os.Stat(filename)
+		if err == nil {
+			err = seedDiaryHistory(user, entryDate, *cached, info.ModTime())
+		}
+		if err != nil {
+			fmt.Printf("Error seeding history for %s: %v\n", user.Username, err)
+		}
+	}
 
 	if err := os.WriteFile(filename, jsonData, 0644); err != nil {
 		return fmt.Errorf("failed to write JSON file for %s: %v", user.Username, err)
 	}
 
 	fmt.Printf("Saved data for %s to %s\n", user.Username, filename)
+
+	previous, current, changed, err := recordDiaryVersion(user, entryDate, entry)
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		publishDiaryEvent(newDiaryEvent(user, previous, current))
+	}
+
 	return nil
 }
 
@@ -198,14 +284,18 @@ func findLoginButtonID(doc *goquery.Document) string {
 }
 
 func createLoginRequest(formData url.Values) (*http.Request, error) {
-	req, err := http.NewRequest("POST", loginPageURL, strings.NewReader(formData.Encode()))
+	return createPostBackRequest(loginPageURL(), formData)
+}
+
+func createPostBackRequest(pageURL string, formData url.Values) (*http.Request, error) {
+	req, err := http.NewRequest("POST", pageURL, strings.NewReader(formData.Encode()))
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-	req.Header.Set("Referer", loginPageURL)
+	req.Header.Set("Referer", pageURL)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
 	req.Header.Set("Origin", baseURL)
@@ -221,6 +311,9 @@ func extractFoodItem(tr *goquery.Selection) FoodItem {
 	nameLink := tr.Find("td:nth-child(1) a")
 	if nameLink.Length() > 0 {
 		item.Name = strings.TrimSpace(nameLink.Text())
+		if href, exists := nameLink.Attr("href"); exists && href != "" {
+			item.URL = absoluteURL(href)
+		}
 	}
 
 	quantityDiv := tr.Find("td:nth-child(1) div.smallText")
@@ -245,6 +338,8 @@ func extractFoodItem(tr *goquery.Selection) FoodItem {
 		item.Calories = strings.TrimSpace(tds.Eq(3).Text())
 	}
 
+	applyFoodMetadata(&item)
+
 	return item
 }
 
@@ -352,11 +447,11 @@ func getUserDiaryEntryMonth(client *http.Client, user User) ([]DiaryEntry, error
 
 		go func() {
 			defer wg.Done()
-			filename := filepath.Join(OutputDir, fmt.Sprintf("%s_%s.json", user.Username, date.Format("2006-01-02")))
+			filename := diaryEntryFilename(user, date)
 
 			diaryEntry := readDiaryEntryFromFile(filename)
 
-			if diaryEntry != nil {
+			if diaryEntry != nil && isFinalDiaryEntry(*diaryEntry) {
 				fmt.Printf("Found diary entry for %s in file: %s\n", user.Username, filename)
 				mu.Lock()
 				detailedEntries = append(detailedEntries, *diaryEntry)
@@ -364,39 +459,12 @@ func getUserDiaryEntryMonth(client *http.Client, user User) ([]DiaryEntry, error
 				return
 			}
 
-			dateID := convertDateToId(date)
-			foodDiaryURL := fmt.Sprintf("https://www.fatsecret.com.br/Diary.aspx?pa=fj&id=%s&dt=%s", user.ID, dateID)
-			fmt.Printf("Accessing food journal for %s...\n", user.Username)
-
-			foodDiaryResp, err := client.Get(foodDiaryURL)
+			detailedEntry, err := fetchDiaryEntry(client, user, date)
 			if err != nil {
 				fmt.Printf("Error accessing food diary for %s: %v\n", user.Username, err)
 				return
 			}
 
-			defer foodDiaryResp.Body.Close()
-
-			foodDiaryDoc, err := goquery.NewDocumentFromReader(foodDiaryResp.Body)
-			if err != nil {
-				fmt.Printf("Error parsing food diary for %s: %v\n", user.Username, err)
-				return
-			}
-
-			detailedEntry := extractDetailedDiaryEntry(foodDiaryDoc)
-			detailedEntry.Date = date.Format("02/01/2006")
-
-			fmt.Printf("\n----- Food diary for %s (%s) -----\n", user.Username, detailedEntry.Date)
-			fmt.Printf("Calories: %s\n", detailedEntry.Calories)
-			fmt.Printf("IDR: %s\n", detailedEntry.IDR)
-			fmt.Printf("Fat: %s g\n", detailedEntry.Fat)
-			fmt.Printf("Protein: %s g\n", detailedEntry.Protein)
-			fmt.Printf("Carbs: %s g\n", detailedEntry.Carbs)
-
-			fmt.Println("\nMeal summary:")
-			for _, meal := range detailedEntry.Meals {
-				fmt.Printf("- %s: %s cal, %d items\n", meal.Name, meal.Calories, len(meal.Items))
-			}
-
 			mu.Lock()
 			detailedEntries = append(detailedEntries, detailedEntry)
 			mu.Unlock()
@@ -415,17 +483,21 @@ func getUserDiaryEntryMonth(client *http.Client, user User) ([]DiaryEntry, error
 }
 
 func getUserDiaryEntry(client *http.Client, user User, date time.Time) (DiaryEntry, error) {
-	filename := filepath.Join(OutputDir, fmt.Sprintf("%s_%s.json", user.Username, date.Format("2006-01-02")))
+	filename := diaryEntryFilename(user, date)
 
 	diaryEntry := readDiaryEntryFromFile(filename)
 
-	if diaryEntry != nil {
+	if diaryEntry != nil && isFinalDiaryEntry(*diaryEntry) {
 		fmt.Printf("Found diary entry for %s in file: %s\n", user.Username, filename)
 		return *diaryEntry, nil
 	}
 
+	return fetchDiaryEntry(client, user, date)
+}
+
+func fetchDiaryEntry(client *http.Client, user User, date time.Time) (DiaryEntry, error) {
 	dateID := convertDateToId(date)
-	foodDiaryURL := fmt.Sprintf("https://www.fatsecret.com.br/Diary.aspx?pa=fj&id=%s&dt=%s", user.ID, dateID)
+	foodDiaryURL := fmt.Sprintf("%s/Diary.aspx?pa=fj&id=%s&dt=%s", baseURL, user.ID, dateID)
 	fmt.Printf("Accessing food journal for %s...\n", user.Username)
 
 	foodDiaryResp, err := client.Get(foodDiaryURL)
@@ -442,21 +514,81 @@ func getUserDiaryEntry(client *http.Client, user User, date time.Time) (DiaryEnt
 	detailedEntry := extractDetailedDiaryEntry(foodDiaryDoc)
 	detailedEntry.Date = date.Format("02/01/2006")
 
+	reconciliation := ReconcileTotals(detailedEntry)
+	detailedEntry.Reconciliation = &reconciliation
+
+	enrichFoodItems(client, detailedEntry.Meals)
+
+	library, err := getMemberLibrary(client, user)
+	if err != nil {
+		fmt.Printf("Error accessing member library for %s: %v\n", user.Username, err)
+	} else {
+		linkLibraryItems(library, detailedEntry.Meals)
+	}
+
+	summary, err := getUserDailySummary(client, user, date)
+	if err != nil {
+		fmt.Printf("Error accessing nutrition report for %s: %v\n", user.Username, err)
+	} else {
+		summary.RDI = detailedEntry.IDR
+		if summary.CalorieGoal == "" {
+			summary.CalorieGoal = deriveCalorieGoal(detailedEntry.Calories, detailedEntry.IDR)
+		}
+		detailedEntry.Summary = &summary
+	}
+
+	exercise, err := getUserExerciseEntry(client, user, date)
+	if err != nil {
+		fmt.Printf("Error accessing exercise diary for %s: %v\n", user.Username, err)
+	} else {
+		detailedEntry.Exercise = &exercise
+		detailedEntry.NetCalories = calculateNetCalories(detailedEntry.Calories, exercise.Calories)
+	}
+
 	fmt.Printf("\n----- Food diary for %s (%s) -----\n", user.Username, detailedEntry.Date)
 	fmt.Printf("Calories: %s\n", detailedEntry.Calories)
 	fmt.Printf("IDR: %s\n", detailedEntry.IDR)
 	fmt.Printf("Fat: %s g\n", detailedEntry.Fat)
 	fmt.Printf("Protein: %s g\n", detailedEntry.Protein)
 	fmt.Printf("Carbs: %s g\n", detailedEntry.Carbs)
+	if detailedEntry.Exercise != nil {
+		fmt.Printf("Burned: %s\n", detailedEntry.Exercise.Calories)
+		fmt.Printf("Net calories: %s\n", detailedEntry.NetCalories)
+	}
 
 	fmt.Println("\nMeal summary:")
 	for _, meal := range detailedEntry.Meals {
 		fmt.Printf("- %s: %s cal, %d items\n", meal.Name, meal.Calories, len(meal.Items))
 	}
 
+	for _, discrepancy := range reconciliation.Discrepancies {
+		scope := discrepancy.Meal
+		if scope == "" {
+			scope = "day"
+		}
+		fmt.Printf("Totals mismatch (%s %s): scraped %s, items add up to %s\n", scope, discrepancy.Field, formatTotal(discrepancy.Scraped), formatTotal(discrepancy.Computed))
+	}
+
 	return detailedEntry, nil
 }
 
+// isFinalDiaryEntry reports whether a cached entry was scraped after the day it
+// describes. Entries scraped on the day itself may still be logged into, so
+// they are scraped again and versioned.
+func isFinalDiaryEntry(entry DiaryEntry) bool {
+	date, err := time.Parse("02/01/2006", entry.Date)
+	if err != nil {
+		return false
+	}
+
+	scrapedAt, err := time.Parse("02/01/2006", entry.Timestamp)
+	if err != nil {
+		return false
+	}
+
+	return scrapedAt.After(date)
+}
+
 func readDiaryEntryFromFile(filename string) *DiaryEntry {
 	file, err := os.ReadFile(filename)
 	if err != nil {
@@ -483,13 +615,14 @@ func loginToFatSecret(username, password string) (*http.Client, error) {
 	}
 
 	client := &http.Client{
-		Jar: jar,
+		Jar:       jar,
+		Transport: httpTransport,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
 	}
 
-	resp, err := client.Get(loginPageURL)
+	resp, err := client.Get(loginPageURL())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get login page: %v", err)
 	}
@@ -545,7 +678,8 @@ func loginToFatSecret(username, password string) (*http.Client, error) {
 
 		// Return a client with the authenticated cookies
 		followClient := &http.Client{
-			Jar: jar,
+			Jar:       jar,
+			Transport: httpTransport,
 		}
 
 		return followClient, nil
@@ -554,14 +688,29 @@ func loginToFatSecret(username, password string) (*http.Client, error) {
 	return nil, fmt.Errorf("login failed - no redirect detected")
 }
 
-func ScrapeFatSecret(username, password string, users []User, date ...time.Time) map[string][]DiaryEntry {
+// ScrapeFatSecret scrapes users on demand, e.g. for /api/diary. It leaves the
+// scrape status alone: that describes the full runs started by RunScraper.
+func ScrapeFatSecret(username, password string, users []User, date ...time.Time) (map[string][]DiaryEntry, error) {
+	return scrapeUsers(username, password, users, false, date...)
+}
+
+// scrapeUsers logs in and scrapes every user. A tracked run is recorded in
+// the scrape status once the login succeeds, and only one may run at a time.
+func scrapeUsers(username, password string, users []User, tracked bool, date ...time.Time) (map[string][]DiaryEntry, error) {
 	if len(users) == 0 {
-		return make(map[string][]DiaryEntry)
+		return make(map[string][]DiaryEntry), nil
 	}
 
 	client, err := loginToFatSecret(username, password)
 	if err != nil {
-		log.Fatalf("Failed to login: %v", err)
+		return nil, fmt.Errorf("failed to login: %v", err)
+	}
+
+	if tracked {
+		if !startScrapeStatus() {
+			return nil, ErrScrapeRunning
+		}
+		defer finishScrapeStatus()
 	}
 
 	var wg sync.WaitGroup
@@ -573,53 +722,52 @@ func ScrapeFatSecret(username, password string, users []User, date ...time.Time)
 		wg.Add(1)
 		go func(user User) {
 			defer wg.Done()
-			if len(date) > 0 {
-				entry, err := getUserDiaryEntry(client, user, date[0])
 
-				if err != nil {
-					fmt.Printf("Error getting diary for %s: %v\n", user.Username, err)
-				}
-
-				if entry.Date != "" {
-					mu.Lock()
-					userEntries[user.Username] = append(userEntries[user.Username], entry)
-					mu.Unlock()
-					if err := saveUserDataToJSON(user, entry); err != nil {
-						fmt.Println(err)
-					}
-				}
+			var entries []DiaryEntry
+			var err error
+			if len(date) > 0 {
+				var entry DiaryEntry
+				entry, err = getUserDiaryEntry(client, user, date[0])
+				entries = []DiaryEntry{entry}
 			} else {
-				entries, err := getUserDiaryEntryMonth(client, user)
+				entries, err = getUserDiaryEntryMonth(client, user)
+			}
 
-				if err != nil {
-					fmt.Printf("Error getting diary for %s: %v\n", user.Username, err)
-					return
+			if err != nil {
+				fmt.Printf("Error getting diary for %s: %v\n", user.Username, err)
+			}
+
+			scraped := []DiaryEntry{}
+			for _, entry := range entries {
+				if entry.Date == "" {
+					continue
 				}
+				scraped = append(scraped, entry)
 
-				for _, entry := range entries {
-					if entry.Date != "" {
-						mu.Lock()
-						userEntries[user.Username] = append(userEntries[user.Username], entry)
-						mu.Unlock()
+				mu.Lock()
+				userEntries[user.Username] = append(userEntries[user.Username], entry)
+				mu.Unlock()
 
-						if err := saveUserDataToJSON(user, entry); err != nil {
-							fmt.Println(err)
-						}
-					}
+				if err := saveUserDataToJSON(user, entry); err != nil {
+					fmt.Println(err)
 				}
 			}
 
-			if err != nil {
-				fmt.Printf("Error getting diary for %s: %v\n", user.Username, err)
-				return
+			anomalies := checkScrapedAnomalies(user, scraped)
+			if tracked {
+				recordUserScrape(user, len(scraped), len(anomalies), err)
 			}
 		}(user)
 	}
 
 	wg.Wait()
 
+	// A CLI scrape exits right after this returns, so let the webhooks for
+	// the days it stored go out first.
+	FlushWebhooks()
+
 	fmt.Println("\nLogin and data extraction successful!")
-	return userEntries
+	return userEntries, nil
 }
 
 func RunScraper(username, password string) {
@@ -628,8 +776,16 @@ func RunScraper(username, password string) {
 		log.Fatalf("Failed to load users: %v", err)
 	}
 
-	entries := ScrapeFatSecret(username, password, users)
+	entries, err := scrapeUsers(username, password, users, true)
+	if err != nil {
+		log.Fatalf("Failed to scrape: %v", err)
+	}
+
+	anomalies := 0
+	for _, user := range CurrentScrapeStatus().Users {
+		anomalies += user.Anomalies
+	}
 
-	fmt.Printf("\nSummary: Retrieved entries for %d users\n", len(entries))
+	fmt.Printf("\nSummary: Retrieved entries for %d users, %d anomalies flagged\n", len(entries), anomalies)
 	fmt.Printf("JSON files saved in the '%s' directory\n", OutputDir)
 }
@@ -0,0 +1,69 @@
+package scraper
+
+import "strings"
+
+// MealSlot is the locale-independent name of a diary meal. FatSecret labels
+// the same four meals in the account's language.
+type MealSlot string
+
+const (
+	SlotBreakfast MealSlot = "breakfast"
+	SlotLunch     MealSlot = "lunch"
+	SlotDinner    MealSlot = "dinner"
+	SlotSnacks    MealSlot = "snacks"
+	SlotOther     MealSlot = "other"
+)
+
+// MealSlots lists the slots in the order they are eaten.
+var MealSlots = []MealSlot{SlotBreakfast, SlotLunch, SlotDinner, SlotSnacks, SlotOther}
+
+var mealSlotNames = map[string]MealSlot{
+	"breakfast":        SlotBreakfast,
+	"café da manhã":    SlotBreakfast,
+	"cafe da manha":    SlotBreakfast,
+	"pequeno-almoço":   SlotBreakfast,
+	"pequeno almoço":   SlotBreakfast,
+	"desayuno":         SlotBreakfast,
+	"petit-déjeuner":   SlotBreakfast,
+	"petit déjeuner":   SlotBreakfast,
+	"frühstück":        SlotBreakfast,
+	"colazione":        SlotBreakfast,
+	"lunch":            SlotLunch,
+	"almoço":           SlotLunch,
+	"almoco":           SlotLunch,
+	"almuerzo":         SlotLunch,
+	"comida":           SlotLunch,
+	"déjeuner":         SlotLunch,
+	"mittagessen":      SlotLunch,
+	"pranzo":           SlotLunch,
+	"dinner":           SlotDinner,
+	"jantar":           SlotDinner,
+	"cena":             SlotDinner,
+	"dîner":            SlotDinner,
+	"abendessen":       SlotDinner,
+	"snacks":           SlotSnacks,
+	"snack":            SlotSnacks,
+	"snacks/other":     SlotSnacks,
+	"lanches":          SlotSnacks,
+	"lanche":           SlotSnacks,
+	"lanches/outros":   SlotSnacks,
+	"merienda":         SlotSnacks,
+	"meriendas":        SlotSnacks,
+	"aperitivos/otros": SlotSnacks,
+	"collations":       SlotSnacks,
+	"snacks/autres":    SlotSnacks,
+	"snacks/sonstiges": SlotSnacks,
+	"spuntini":         SlotSnacks,
+}
+
+// CanonicalMealSlot maps a meal name as shown by FatSecret, in any of the
+// supported languages, to its slot. Unknown names map to SlotOther.
+func CanonicalMealSlot(name string) MealSlot {
+	name = strings.Join(strings.Fields(strings.ToLower(name)), " ")
+	name = strings.ReplaceAll(name, " / ", "/")
+
+	if slot, ok := mealSlotNames[name]; ok {
+		return slot
+	}
+	return SlotOther
+}
@@ -0,0 +1,41 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var thousandsPattern = regexp.MustCompile(`^[1-9]\d{0,2}(\.\d{3})+$`)
+
+// ParseNumber converts a value as shown on fatsecret.com.br ("1.845", "12,50",
+// "30 g") into a float. A dot followed by three digits is a thousands
+// separator unless the integer part is 0, so "0.500" stays 0.5. Empty or
+// unparseable values return 0.
+func ParseNumber(value string) float64 {
+	value = strings.TrimSpace(value)
+
+	end := 0
+	for end < len(value) && strings.ContainsRune("0123456789.,-", rune(value[end])) {
+		end++
+	}
+	value = value[:end]
+
+	if strings.Contains(value, ",") {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.ReplaceAll(value, ",", ".")
+	} else if thousandsPattern.MatchString(value) {
+		value = strings.ReplaceAll(value, ".", "")
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+
+	return number
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,23 @@
+package scraper
+
+import "testing"
+
+func TestParseNumber(t *testing.T) {
+	for value, want := range map[string]float64{
+		"1.845":     1845,
+		"60,5":      60.5,
+		"1.234,5":   1234.5,
+		"0.5":       0.5,
+		"0.500":     0.5,
+		"12.5":      12.5,
+		"1.234.567": 1234567,
+		"30 g":      30,
+		" 2.100 ":   2100,
+		"":          0,
+		"-":         0,
+	} {
+		if got := ParseNumber(value); got != want {
+			t.Errorf("ParseNumber(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
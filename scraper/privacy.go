@@ -0,0 +1,15 @@
+package scraper
+
+// Privacy holds what a user shares with the rest of the team. Leaderboards
+// leave out users who opted out and list anonymous ones without their
+// username.
+type Privacy struct {
+	LeaderboardOptOut bool `json:"leaderboard_opt_out,omitempty"`
+	Anonymous         bool `json:"anonymous,omitempty"`
+}
+
+func UpdateUserPrivacy(username string, privacy Privacy) (User, bool, error) {
+	return updateUser(username, func(user *User) {
+		user.Privacy = &privacy
+	})
+}
@@ -0,0 +1,337 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type RecipeIngredient struct {
+	Name     string `json:"name"`
+	Quantity string `json:"quantity"`
+	URL      string `json:"url,omitempty"`
+}
+
+type Recipe struct {
+	URL         string             `json:"url"`
+	Name        string             `json:"name"`
+	Servings    string             `json:"servings"`
+	Ingredients []RecipeIngredient `json:"ingredients"`
+	PerServing  FoodNutrients      `json:"per_serving"`
+}
+
+type CustomFood struct {
+	URL       string        `json:"url"`
+	Name      string        `json:"name"`
+	Nutrients FoodNutrients `json:"nutrients"`
+}
+
+// SavedMeal is a group of foods the member saved to log together. Its items
+// are listed like diary items, with their own macros.
+type SavedMeal struct {
+	URL       string        `json:"url"`
+	Name      string        `json:"name"`
+	Items     []FoodItem    `json:"items"`
+	Nutrients FoodNutrients `json:"nutrients"`
+}
+
+// MemberLibrary is everything a member has defined themselves: recipes,
+// saved meals and custom foods.
+type MemberLibrary struct {
+	Recipes     []Recipe     `json:"recipes"`
+	SavedMeals  []SavedMeal  `json:"saved_meals"`
+	CustomFoods []CustomFood `json:"custom_foods"`
+}
+
+const memberLibraryTTL = time.Hour
+
+var servingsPattern = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*porç`)
+
+type memberLibraryEntry struct {
+	once    sync.Once
+	expires time.Time
+	library MemberLibrary
+	err     error
+}
+
+// memberLibraryCache works like foodCache: concurrent scrapes of one member
+// wait for a single fetch instead of each fetching the library.
+var memberLibraryCache = struct {
+	sync.Mutex
+	entries map[string]*memberLibraryEntry
+}{entries: make(map[string]*memberLibraryEntry)}
+
+func fetchDocument(client *http.Client, pageURL string) (*goquery.Document, error) {
+	resp, err := client.Get(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d for %s", resp.StatusCode, pageURL)
+	}
+
+	return goquery.NewDocumentFromReader(resp.Body)
+}
+
+func extractLibraryLinks(doc *goquery.Document) []string {
+	links := []string{}
+	seen := make(map[string]bool)
+
+	doc.Find("table.generic a.prominent").Each(func(_ int, s *goquery.Selection) {
+		href, exists := s.Attr("href")
+		if !exists || href == "" {
+			return
+		}
+
+		link := absoluteURL(href)
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	})
+
+	return links
+}
+
+// extractIngredients reads recipe ingredient rows. A linked ingredient is
+// named by its link and the rest of the row is its quantity; an unlinked one
+// keeps the whole row as both.
+func extractIngredients(rows *goquery.Selection) []RecipeIngredient {
+	ingredients := []RecipeIngredient{}
+	rows.Each(func(_ int, s *goquery.Selection) {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		ingredient := RecipeIngredient{Name: text, Quantity: text}
+
+		link := s.Find("a").First()
+		if link.Length() > 0 {
+			ingredient.Name = strings.Join(strings.Fields(link.Text()), " ")
+			ingredient.Quantity = strings.TrimSpace(strings.Replace(text, ingredient.Name, "", 1))
+			if href, exists := link.Attr("href"); exists && href != "" {
+				ingredient.URL = absoluteURL(href)
+			}
+		}
+
+		if ingredient.Name != "" {
+			ingredients = append(ingredients, ingredient)
+		}
+	})
+
+	return ingredients
+}
+
+func extractRecipe(doc *goquery.Document) Recipe {
+	recipe := Recipe{}
+
+	recipe.Name = strings.TrimSpace(doc.Find("h1").First().Text())
+
+	if match := servingsPattern.FindStringSubmatch(doc.Find("div.recipe_servings, div.serving_size").Text()); match != nil {
+		recipe.Servings = match[1]
+	}
+
+	recipe.Ingredients = extractIngredients(doc.Find("li.ingredient, div.ingredient"))
+	recipe.PerServing = extractFoodNutrients(doc)
+
+	return recipe
+}
+
+func extractCustomFood(doc *goquery.Document) CustomFood {
+	return CustomFood{
+		Name:      strings.TrimSpace(doc.Find("h1").First().Text()),
+		Nutrients: extractFoodNutrients(doc),
+	}
+}
+
+// extractSavedMeal reads a saved meal page. Its foods are rows of the same
+// nutrition table as the diary, one row per food with the fat, carbs,
+// protein and calories cells; header and total rows have no td.normal cells.
+func extractSavedMeal(doc *goquery.Document) SavedMeal {
+	meal := SavedMeal{
+		Name:      strings.TrimSpace(doc.Find("h1").First().Text()),
+		Items:     []FoodItem{},
+		Nutrients: extractFoodNutrients(doc),
+	}
+
+	doc.Find("table.foodsNutritionTbl tr").Has("td.normal").Each(func(_ int, s *goquery.Selection) {
+		item := extractFoodItem(s)
+		if item.Name != "" {
+			meal.Items = append(meal.Items, item)
+		}
+	})
+
+	return meal
+}
+
+func getUserRecipes(client *http.Client, user User) ([]Recipe, error) {
+	listURL := fmt.Sprintf("%s/Default.aspx?pa=memr&id=%s", baseURL, user.ID)
+	fmt.Printf("Accessing recipes for %s...\n", user.Username)
+
+	doc, err := fetchDocument(client, listURL)
+	if err != nil {
+		return nil, err
+	}
+
+	recipes := []Recipe{}
+	for _, link := range extractLibraryLinks(doc) {
+		recipeDoc, err := fetchDocument(client, link)
+		if err != nil {
+			fmt.Printf("Error accessing recipe %s: %v\n", link, err)
+			continue
+		}
+
+		recipe := extractRecipe(recipeDoc)
+		recipe.URL = link
+		recipes = append(recipes, recipe)
+	}
+
+	return recipes, nil
+}
+
+func getUserCustomFoods(client *http.Client, user User) ([]CustomFood, error) {
+	listURL := fmt.Sprintf("%s/Default.aspx?pa=memf&id=%s", baseURL, user.ID)
+	fmt.Printf("Accessing custom foods for %s...\n", user.Username)
+
+	doc, err := fetchDocument(client, listURL)
+	if err != nil {
+		return nil, err
+	}
+
+	foods := []CustomFood{}
+	for _, link := range extractLibraryLinks(doc) {
+		foodDoc, err := fetchDocument(client, link)
+		if err != nil {
+			fmt.Printf("Error accessing custom food %s: %v\n", link, err)
+			continue
+		}
+
+		food := extractCustomFood(foodDoc)
+		food.URL = link
+		foods = append(foods, food)
+	}
+
+	return foods, nil
+}
+
+// getUserSavedMeals scrapes the member's saved meals. Each meal page lists
+// its foods like a diary meal, with the meal's totals below.
+func getUserSavedMeals(client *http.Client, user User) ([]SavedMeal, error) {
+	listURL := fmt.Sprintf("%s/Default.aspx?pa=memm&id=%s", baseURL, user.ID)
+	fmt.Printf("Accessing saved meals for %s...\n", user.Username)
+
+	doc, err := fetchDocument(client, listURL)
+	if err != nil {
+		return nil, err
+	}
+
+	meals := []SavedMeal{}
+	for _, link := range extractLibraryLinks(doc) {
+		mealDoc, err := fetchDocument(client, link)
+		if err != nil {
+			fmt.Printf("Error accessing saved meal %s: %v\n", link, err)
+			continue
+		}
+
+		meal := extractSavedMeal(mealDoc)
+		meal.URL = link
+		meals = append(meals, meal)
+	}
+
+	return meals, nil
+}
+
+func fetchMemberLibrary(client *http.Client, user User) (MemberLibrary, error) {
+	recipes, err := getUserRecipes(client, user)
+	if err != nil {
+		return MemberLibrary{}, fmt.Errorf("failed to get recipes: %v", err)
+	}
+
+	savedMeals, err := getUserSavedMeals(client, user)
+	if err != nil {
+		return MemberLibrary{}, fmt.Errorf("failed to get saved meals: %v", err)
+	}
+
+	customFoods, err := getUserCustomFoods(client, user)
+	if err != nil {
+		return MemberLibrary{}, fmt.Errorf("failed to get custom foods: %v", err)
+	}
+
+	return MemberLibrary{Recipes: recipes, SavedMeals: savedMeals, CustomFoods: customFoods}, nil
+}
+
+func getMemberLibrary(client *http.Client, user User) (MemberLibrary, error) {
+	memberLibraryCache.Lock()
+	entry, ok := memberLibraryCache.entries[user.ID]
+	if !ok || time.Now().After(entry.expires) {
+		entry = &memberLibraryEntry{expires: time.Now().Add(memberLibraryTTL)}
+		memberLibraryCache.entries[user.ID] = entry
+	}
+	memberLibraryCache.Unlock()
+
+	entry.once.Do(func() {
+		entry.library, entry.err = fetchMemberLibrary(client, user)
+	})
+
+	if entry.err != nil {
+		memberLibraryCache.Lock()
+		if memberLibraryCache.entries[user.ID] == entry {
+			delete(memberLibraryCache.entries, user.ID)
+		}
+		memberLibraryCache.Unlock()
+	}
+
+	return entry.library, entry.err
+}
+
+// linkLibraryItems points diary items at the recipe, saved meal or custom
+// food they were logged from, matching by link first and by name otherwise.
+func linkLibraryItems(library MemberLibrary, meals []MealData) {
+	for i := range meals {
+		for j := range meals[i].Items {
+			item := &meals[i].Items[j]
+
+			for _, recipe := range library.Recipes {
+				if (item.URL != "" && item.URL == recipe.URL) || strings.EqualFold(item.Name, recipe.Name) {
+					item.RecipeURL = recipe.URL
+					break
+				}
+			}
+
+			if item.RecipeURL != "" {
+				continue
+			}
+
+			for _, meal := range library.SavedMeals {
+				if (item.URL != "" && item.URL == meal.URL) || strings.EqualFold(item.Name, meal.Name) {
+					item.SavedMealURL = meal.URL
+					break
+				}
+			}
+
+			if item.SavedMealURL != "" {
+				continue
+			}
+
+			for _, food := range library.CustomFoods {
+				if (item.URL != "" && item.URL == food.URL) || strings.EqualFold(item.Name, food.Name) {
+					item.CustomFoodURL = food.URL
+					break
+				}
+			}
+		}
+	}
+}
+
+func ScrapeMemberLibrary(username, password string, user User) (MemberLibrary, error) {
+	client, err := loginToFatSecret(username, password)
+	if err != nil {
+		return MemberLibrary{}, fmt.Errorf("failed to login: %v", err)
+	}
+
+	return getMemberLibrary(client, user)
+}
@@ -0,0 +1,110 @@
+package scraper
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+const libraryListPage = `<html><body><table class="generic">
+<tr><td><a class="prominent" href="/receitas/bolo-de-banana">Bolo de banana</a></td></tr>
+<tr><td><a class="prominent" href="https://www.fatsecret.com.br/receitas/panqueca">Panqueca</a></td></tr>
+<tr><td><a class="prominent" href="/receitas/bolo-de-banana">Bolo de banana</a> <a href="/receitas/bolo-de-banana?edit=1">editar</a></td></tr>
+</table></body></html>`
+
+const recipePage = `<html><body><h1>Bolo de banana</h1>
+<div class="recipe_servings">Rende 8 porções</div>
+<ul>
+<li class="ingredient"><a href="/calorias-nutrição/genérico/banana">Banana</a> 3 médias</li>
+<li class="ingredient"><a href="/calorias-nutrição/genérico/aveia">Aveia em flocos</a>
+  1 1/2 xícaras</li>
+<li class="ingredient">1 pitada de canela</li>
+</ul>
+<div class="nutrition_facts"><div class="serving_size_value">1 porção</div>
+<div class="nutrient left">Energia</div><div class="nutrient right">180 kcal</div>
+<div class="nutrient left">Carboidratos</div><div class="nutrient right">32g</div>
+</div></body></html>`
+
+const customFoodPage = `<html><body><h1> Whey da Ana </h1>
+<div class="nutrition_facts"><div class="serving_size_value">1 scoop (30 g)</div>
+<div class="nutrient left">Calorias</div><div class="nutrient right">120 kcal</div>
+<div class="nutrient left">Proteínas</div><div class="nutrient right">24g</div>
+</div></body></html>`
+
+const savedMealPage = `<html><body><h1>Café de sempre</h1>
+<table class="generic foodsTbl">
+<tr><td><table class="foodsNutritionTbl"><tr>
+<td class="greytitlex">Café de sempre</td><td class="sub">9</td><td class="sub">52</td><td class="sub">18</td><td class="sub">360</td>
+</tr></table></td></tr>
+<tr><td class="borderLeft borderRight"><table class="foodsNutritionTbl"><tr>
+<td><a href="/calorias-nutrição/genérico/pão-francês">Pão Francês</a><div class="smallText">1 unidade</div></td>
+<td class="normal">2</td><td class="normal">29</td><td class="normal">4,5</td><td class="normal">150</td>
+</tr></table></td></tr>
+<tr><td class="borderLeft borderRight"><table class="foodsNutritionTbl"><tr>
+<td><a href="/calorias-nutrição/genérico/ovo-mexido">Ovo Mexido</a><div class="smallText">2 grandes</div></td>
+<td class="normal">7</td><td class="normal">23</td><td class="normal">13,5</td><td class="normal">210</td>
+</tr></table></td></tr>
+</table>
+<div class="nutrition_facts">
+<div class="nutrient left">Energia</div><div class="nutrient right">360 kcal</div>
+</div></body></html>`
+
+func TestExtractLibraryLinks(t *testing.T) {
+	want := []string{
+		"https://www.fatsecret.com.br/receitas/bolo-de-banana",
+		"https://www.fatsecret.com.br/receitas/panqueca",
+	}
+	if got := extractLibraryLinks(parseFixture(t, libraryListPage)); !slices.Equal(got, want) {
+		t.Errorf("extractLibraryLinks = %q, want %q", got, want)
+	}
+}
+
+func TestExtractRecipe(t *testing.T) {
+	recipe := extractRecipe(parseFixture(t, recipePage))
+
+	if recipe.Name != "Bolo de banana" || recipe.Servings != "8" {
+		t.Errorf("recipe = %q serving %q", recipe.Name, recipe.Servings)
+	}
+	want := []RecipeIngredient{
+		{Name: "Banana", Quantity: "3 médias", URL: "https://www.fatsecret.com.br/calorias-nutrição/genérico/banana"},
+		{Name: "Aveia em flocos", Quantity: "1 1/2 xícaras", URL: "https://www.fatsecret.com.br/calorias-nutrição/genérico/aveia"},
+		{Name: "1 pitada de canela", Quantity: "1 pitada de canela"},
+	}
+	if !slices.Equal(recipe.Ingredients, want) {
+		t.Errorf("ingredients = %+v, want %+v", recipe.Ingredients, want)
+	}
+	if recipe.PerServing.Calories != "180 kcal" || recipe.PerServing.Carbs != "32g" {
+		t.Errorf("per serving = %+v", recipe.PerServing)
+	}
+}
+
+func TestExtractCustomFood(t *testing.T) {
+	food := extractCustomFood(parseFixture(t, customFoodPage))
+
+	want := CustomFood{Name: "Whey da Ana", Nutrients: FoodNutrients{ServingSize: "1 scoop (30 g)", Calories: "120 kcal", Protein: "24g"}}
+	if !reflect.DeepEqual(food, want) {
+		t.Errorf("custom food = %+v, want %+v", food, want)
+	}
+}
+
+func TestExtractSavedMeal(t *testing.T) {
+	meal := extractSavedMeal(parseFixture(t, savedMealPage))
+
+	if meal.Name != "Café de sempre" || meal.Nutrients.Calories != "360 kcal" {
+		t.Errorf("saved meal = %q with %+v", meal.Name, meal.Nutrients)
+	}
+	if len(meal.Items) != 2 {
+		t.Fatalf("got %d items %+v, want one per food row", len(meal.Items), meal.Items)
+	}
+
+	bread, eggs := meal.Items[0], meal.Items[1]
+	if bread.Name != "Pão Francês" || bread.Quantity != "1 unidade" || bread.Fat != "2" || bread.Carbs != "29" || bread.Protein != "4,5" || bread.Calories != "150" {
+		t.Errorf("first item = %+v", bread)
+	}
+	if eggs.Name != "Ovo Mexido" || eggs.ServingAmount != 2 || eggs.ServingUnit != "unidade grande" || eggs.Calories != "210" {
+		t.Errorf("second item = %+v", eggs)
+	}
+	if eggs.URL != "https://www.fatsecret.com.br/calorias-nutrição/genérico/ovo-mexido" || eggs.FoodID != "genérico/ovo-mexido" {
+		t.Errorf("second item link = %q id %q", eggs.URL, eggs.FoodID)
+	}
+}
@@ -0,0 +1,182 @@
+package scraper
+
+import (
+	"fmt"
+	"math"
+)
+
+type TotalsMode string
+
+const (
+	TotalsScraped  TotalsMode = "scraped"
+	TotalsComputed TotalsMode = "computed"
+)
+
+func ParseTotalsMode(value string) (TotalsMode, error) {
+	switch TotalsMode(value) {
+	case "":
+		return TotalsScraped, nil
+	case TotalsScraped, TotalsComputed:
+		return TotalsMode(value), nil
+	}
+	return "", fmt.Errorf("unknown totals %q, use scraped or computed", value)
+}
+
+type Totals struct {
+	Calories float64 `json:"calories"`
+	Fat      float64 `json:"fat"`
+	Carbs    float64 `json:"carbs"`
+	Protein  float64 `json:"protein"`
+}
+
+type TotalsDiscrepancy struct {
+	Meal       string  `json:"meal,omitempty"`
+	Field      string  `json:"field"`
+	Scraped    float64 `json:"scraped"`
+	Computed   float64 `json:"computed"`
+	Difference float64 `json:"difference"`
+}
+
+// Reconciliation holds the totals recomputed from the item rows and where
+// they disagree with the totals FatSecret printed. Meals has one entry per
+// meal, in diary order; discrepancies without a meal are for the whole day.
+type Reconciliation struct {
+	Day           Totals              `json:"day"`
+	Meals         []Totals            `json:"meals"`
+	Discrepancies []TotalsDiscrepancy `json:"discrepancies"`
+}
+
+func (t *Totals) add(calories, fat, carbs, protein string) {
+	t.Calories += ParseNumber(calories)
+	t.Fat += ParseNumber(fat)
+	t.Carbs += ParseNumber(carbs)
+	t.Protein += ParseNumber(protein)
+}
+
+// totalsMismatch reports whether a scraped total and the sum of the rows
+// under it differ by more than rounding: FatSecret rounds every row, so each
+// summed row may be off by half a unit, and the total itself by 1%. It is the
+// one tolerance behind both the reconciliation and the totals anomalies.
+func totalsMismatch(scraped, computed float64, rows int) bool {
+	return math.Abs(computed-scraped) > math.Max(0.5*float64(rows), 0.01*scraped)
+}
+
+// compareTotals records the fields where scraped and computed differ per
+// totalsMismatch. Fields that weren't scraped are skipped.
+func compareTotals(meal string, rows int, scraped [4]string, computed Totals) []TotalsDiscrepancy {
+	discrepancies := []TotalsDiscrepancy{}
+
+	fields := []string{"calories", "fat", "carbs", "protein"}
+	values := []float64{computed.Calories, computed.Fat, computed.Carbs, computed.Protein}
+	for i, field := range fields {
+		if scraped[i] == "" {
+			continue
+		}
+
+		value := ParseNumber(scraped[i])
+		if !totalsMismatch(value, values[i], rows) {
+			continue
+		}
+
+		discrepancies = append(discrepancies, TotalsDiscrepancy{
+			Meal:       meal,
+			Field:      field,
+			Scraped:    value,
+			Computed:   values[i],
+			Difference: values[i] - value,
+		})
+	}
+
+	return discrepancies
+}
+
+// ReconcileTotals sums the items of every meal and the meals of the day and
+// compares them with the scraped totals. Meals without items and days
+// without meals have nothing to compare against and are skipped.
+func ReconcileTotals(entry DiaryEntry) Reconciliation {
+	reconciliation := Reconciliation{
+		Meals:         []Totals{},
+		Discrepancies: []TotalsDiscrepancy{},
+	}
+
+	rows := 0
+	for _, meal := range entry.Meals {
+		var totals Totals
+		for _, item := range meal.Items {
+			totals.add(item.Calories, item.Fat, item.Carbs, item.Protein)
+		}
+		rows += len(meal.Items)
+
+		reconciliation.Meals = append(reconciliation.Meals, totals)
+		reconciliation.Day.Calories += totals.Calories
+		reconciliation.Day.Fat += totals.Fat
+		reconciliation.Day.Carbs += totals.Carbs
+		reconciliation.Day.Protein += totals.Protein
+
+		if len(meal.Items) > 0 {
+			scraped := [4]string{meal.Calories, meal.Fat, meal.Carbs, meal.Protein}
+			reconciliation.Discrepancies = append(reconciliation.Discrepancies, compareTotals(meal.Name, len(meal.Items), scraped, totals)...)
+		}
+	}
+
+	if len(entry.Meals) > 0 {
+		scraped := [4]string{entry.Calories, entry.Fat, entry.Carbs, entry.Protein}
+		reconciliation.Discrepancies = append(reconciliation.Discrepancies, compareTotals("", rows, scraped, reconciliation.Day)...)
+	}
+
+	return reconciliation
+}
+
+func formatTotal(value float64) string {
+	return formatNumber(math.Round(value*100) / 100)
+}
+
+// WithComputedTotals returns a copy of the entry whose day and meal totals
+// are the ones recomputed from the items.
+func WithComputedTotals(entry DiaryEntry) DiaryEntry {
+	reconciliation := entry.Reconciliation
+	if reconciliation == nil {
+		computed := ReconcileTotals(entry)
+		reconciliation = &computed
+	}
+
+	entry.Calories = formatTotal(reconciliation.Day.Calories)
+	entry.Fat = formatTotal(reconciliation.Day.Fat)
+	entry.Carbs = formatTotal(reconciliation.Day.Carbs)
+	entry.Protein = formatTotal(reconciliation.Day.Protein)
+
+	meals := make([]MealData, len(entry.Meals))
+	for i, meal := range entry.Meals {
+		if i < len(reconciliation.Meals) {
+			meal.Calories = formatTotal(reconciliation.Meals[i].Calories)
+			meal.Fat = formatTotal(reconciliation.Meals[i].Fat)
+			meal.Carbs = formatTotal(reconciliation.Meals[i].Carbs)
+			meal.Protein = formatTotal(reconciliation.Meals[i].Protein)
+		}
+		meals[i] = meal
+	}
+	entry.Meals = meals
+
+	return entry
+}
+
+// ApplyEntryTotals returns the entry with the totals picked by mode.
+func ApplyEntryTotals(mode TotalsMode, entry DiaryEntry) DiaryEntry {
+	if mode != TotalsComputed {
+		return entry
+	}
+	return WithComputedTotals(entry)
+}
+
+// ApplyTotals returns the entries with the totals picked by mode.
+func ApplyTotals(mode TotalsMode, entries []DiaryEntry) []DiaryEntry {
+	if mode != TotalsComputed {
+		return entries
+	}
+
+	computed := make([]DiaryEntry, len(entries))
+	for i, entry := range entries {
+		computed[i] = WithComputedTotals(entry)
+	}
+	return computed
+}
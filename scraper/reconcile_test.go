@@ -0,0 +1,46 @@
+package scraper
+
+import "testing"
+
+func TestTotalsChecksAgree(t *testing.T) {
+	items := []FoodItem{
+		{Name: "Arroz", Calories: "130", Fat: "0,3", Carbs: "28", Protein: "2,7"},
+		{Name: "Feijão", Calories: "76", Fat: "0,5", Carbs: "13,6", Protein: "4,8"},
+	}
+
+	for _, test := range []struct {
+		name         string
+		mealCalories string
+		dayCalories  string
+		wantMeal     bool
+		wantDay      bool
+	}{
+		{"rounding", "207", "207", false, false},
+		{"meal off", "260", "206", true, false},
+		{"day off", "206", "250", false, true},
+	} {
+		entry := DiaryEntry{
+			Date: "12/05/2025", Calories: test.dayCalories,
+			Meals: []MealData{{Name: "Almoço", Calories: test.mealCalories, Items: items}},
+		}
+
+		discrepancies := map[string]bool{}
+		for _, discrepancy := range ReconcileTotals(entry).Discrepancies {
+			if discrepancy.Field == "calories" {
+				discrepancies[discrepancy.Meal] = true
+			}
+		}
+
+		rules := map[string]bool{}
+		for _, anomaly := range checkEntryRules(entry) {
+			rules[anomaly.Rule] = true
+		}
+
+		if discrepancies["Almoço"] != test.wantMeal || rules["meal_totals_mismatch"] != test.wantMeal {
+			t.Errorf("%s: meal discrepancy %v, anomaly %v, want %v", test.name, discrepancies["Almoço"], rules["meal_totals_mismatch"], test.wantMeal)
+		}
+		if discrepancies[""] != test.wantDay || rules["day_totals_mismatch"] != test.wantDay {
+			t.Errorf("%s: day discrepancy %v, anomaly %v, want %v", test.name, discrepancies[""], rules["day_totals_mismatch"], test.wantDay)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type FoodSearchResult struct {
+	Name     string `json:"name"`
+	Brand    string `json:"brand,omitempty"`
+	URL      string `json:"url"`
+	FoodID   string `json:"food_id"`
+	Serving  string `json:"serving"`
+	Calories string `json:"calories"`
+	Fat      string `json:"fat"`
+	Carbs    string `json:"carbs"`
+	Protein  string `json:"protein"`
+}
+
+var (
+	searchServingPattern = regexp.MustCompile(`(?i)^\s*por\s+(.+?)\s*-`)
+	searchValuePattern   = regexp.MustCompile(`(?i)(calorias|gorduras|carboidratos|proteínas)\s*:\s*([\d.,]+)`)
+)
+
+// parseSearchSummary reads the one-line nutrition summary under each search
+// result, e.g. "Por 100g - Calorias: 130kcal | Gorduras: 0,28g | ...".
+func parseSearchSummary(result *FoodSearchResult, summary string) {
+	summary = strings.Join(strings.Fields(summary), " ")
+
+	if match := searchServingPattern.FindStringSubmatch(summary); match != nil {
+		result.Serving = match[1]
+	}
+
+	for _, match := range searchValuePattern.FindAllStringSubmatch(summary, -1) {
+		switch strings.ToLower(match[1]) {
+		case "calorias":
+			result.Calories = match[2]
+		case "gorduras":
+			result.Fat = match[2]
+		case "carboidratos":
+			result.Carbs = match[2]
+		case "proteínas":
+			result.Protein = match[2]
+		}
+	}
+}
+
+func extractFoodSearchResults(doc *goquery.Document) []FoodSearchResult {
+	results := []FoodSearchResult{}
+
+	doc.Find("table.generic.searchResult td.borderBottom").Each(func(_ int, s *goquery.Selection) {
+		nameLink := s.Find("a.prominent").First()
+		if nameLink.Length() == 0 {
+			return
+		}
+
+		result := FoodSearchResult{
+			Name:  strings.TrimSpace(nameLink.Text()),
+			Brand: strings.Trim(strings.TrimSpace(s.Find("a.brand").First().Text()), "()"),
+		}
+
+		if href, exists := nameLink.Attr("href"); exists && href != "" {
+			result.URL = absoluteURL(href)
+			result.FoodID, _ = parseFoodURL(result.URL)
+		}
+
+		parseSearchSummary(&result, s.Find("div.smallText").First().Text())
+
+		results = append(results, result)
+	})
+
+	return results
+}
+
+// SearchFoods queries FatSecret's public food database. Pages are zero-based,
+// matching the site's "pg" parameter.
+func SearchFoods(username, password, query string, page int) ([]FoodSearchResult, error) {
+	client, err := loginToFatSecret(username, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to login: %v", err)
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	if page > 0 {
+		params.Set("pg", fmt.Sprintf("%d", page))
+	}
+
+	fmt.Printf("Searching foods for %q...\n", query)
+
+	doc, err := fetchDocument(client, baseURL+"/calorias-nutrição/search?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to search foods: %v", err)
+	}
+
+	return extractFoodSearchResults(doc), nil
+}
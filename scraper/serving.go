@@ -0,0 +1,177 @@
+package scraper
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	servingPattern  = regexp.MustCompile(`^\s*(\d+\s+\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?)\s*(.*)$`)
+	parentheticalRe = regexp.MustCompile(`\s*\(.*\)\s*$`)
+)
+
+var servingUnits = map[string]string{
+	"g":                  "g",
+	"grama":              "g",
+	"gramas":             "g",
+	"kg":                 "kg",
+	"ml":                 "ml",
+	"mililitro":          "ml",
+	"mililitros":         "ml",
+	"l":                  "l",
+	"litro":              "l",
+	"litros":             "l",
+	"unidade":            "unidade",
+	"unidades":           "unidade",
+	"un":                 "unidade",
+	"xícara":             "xícara",
+	"xícaras":            "xícara",
+	"xicara":             "xícara",
+	"xicaras":            "xícara",
+	"porção":             "porção",
+	"porções":            "porção",
+	"fatia":              "fatia",
+	"fatias":             "fatia",
+	"colher de sopa":     "colher de sopa",
+	"colheres de sopa":   "colher de sopa",
+	"colher de chá":      "colher de chá",
+	"colheres de chá":    "colher de chá",
+	"copo":               "copo",
+	"copos":              "copo",
+	"concha":             "concha",
+	"conchas":            "concha",
+	"pedaço":             "pedaço",
+	"pedaços":            "pedaço",
+	"oz":                 "oz",
+	"lata":               "lata",
+	"latas":              "lata",
+	"embalagem":          "embalagem",
+	"embalagens":         "embalagem",
+	"pacote":             "pacote",
+	"pacotes":            "pacote",
+	"pequeno":            "unidade pequena",
+	"pequena":            "unidade pequena",
+	"pequenos":           "unidade pequena",
+	"pequenas":           "unidade pequena",
+	"unidade pequena":    "unidade pequena",
+	"unidades pequenas":  "unidade pequena",
+	"médio":              "unidade média",
+	"média":              "unidade média",
+	"médios":             "unidade média",
+	"médias":             "unidade média",
+	"unidade média":      "unidade média",
+	"unidades médias":    "unidade média",
+	"grande":             "unidade grande",
+	"grandes":            "unidade grande",
+	"unidade grande":     "unidade grande",
+	"unidades grandes":   "unidade grande",
+	"colher de servir":   "colher de servir",
+	"colheres de servir": "colher de servir",
+}
+
+var measuredUnits = map[string]bool{"g": true, "kg": true, "ml": true, "l": true, "oz": true}
+
+var genericBrands = map[string]bool{"genérico": true, "generico": true, "usda": true}
+
+func parseServingAmount(value string) float64 {
+	value = strings.TrimSpace(value)
+
+	whole := 0.0
+	if parts := strings.Fields(value); len(parts) == 2 {
+		whole = ParseNumber(parts[0])
+		value = parts[1]
+	}
+
+	if numerator, denominator, ok := strings.Cut(value, "/"); ok {
+		n, errN := strconv.ParseFloat(numerator, 64)
+		d, errD := strconv.ParseFloat(denominator, 64)
+		if errN != nil || errD != nil || d == 0 {
+			return whole
+		}
+		return whole + n/d
+	}
+
+	return whole + ParseNumber(value)
+}
+
+// parseServing splits a diary quantity such as "1 1/2 xícaras (240 g)" into its
+// amount and a normalized unit. Size-only units such as "médio" keep the size
+// ("unidade média"). Unknown units are kept as written.
+func parseServing(quantity string) (float64, string) {
+	match := servingPattern.FindStringSubmatch(quantity)
+	if match == nil {
+		return 0, ""
+	}
+
+	amount := parseServingAmount(match[1])
+	unit := strings.ToLower(strings.TrimSpace(parentheticalRe.ReplaceAllString(match[2], "")))
+
+	if normalized, ok := servingUnits[unit]; ok {
+		unit = normalized
+	}
+
+	return amount, unit
+}
+
+// parseFoodURL extracts a stable food ID and brand from a food link. Branded
+// foods live under /calorias-nutrição/{brand}/{food}; generic ones use
+// "genérico" as the brand, which is reported as empty.
+func parseFoodURL(foodURL string) (string, string) {
+	parsed, err := url.Parse(foodURL)
+	if err != nil {
+		return "", ""
+	}
+
+	query := parsed.Query()
+	for _, key := range []string{"fid", "foodid", "food_id"} {
+		if id := query.Get(key); id != "" {
+			return id, ""
+		}
+	}
+
+	segments := []string{}
+	for _, segment := range strings.Split(parsed.Path, "/") {
+		if segment != "" {
+			segments = append(segments, strings.ToLower(segment))
+		}
+	}
+
+	if len(segments) < 3 {
+		return strings.Join(segments, "/"), ""
+	}
+
+	brand := segments[1]
+	id := strings.Join(segments[1:], "/")
+	if genericBrands[brand] {
+		return id, ""
+	}
+
+	return id, strings.ReplaceAll(brand, "-", " ")
+}
+
+func portionAmount(foodURL string) float64 {
+	parsed, err := url.Parse(foodURL)
+	if err != nil {
+		return 0
+	}
+	return ParseNumber(parsed.Query().Get("portionamount"))
+}
+
+func applyFoodMetadata(item *FoodItem) {
+	if item.URL != "" {
+		item.FoodID, item.Brand = parseFoodURL(item.URL)
+	}
+
+	item.ServingAmount, item.ServingUnit = parseServing(item.Quantity)
+
+	switch {
+	case portionAmount(item.URL) > 0:
+		item.Servings = portionAmount(item.URL)
+	case item.ServingAmount > 0 && !measuredUnits[item.ServingUnit]:
+		item.Servings = item.ServingAmount
+	case item.Quantity != "":
+		item.Servings = 1
+	}
+}
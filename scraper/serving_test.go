@@ -0,0 +1,58 @@
+package scraper
+
+import "testing"
+
+func TestParseServing(t *testing.T) {
+	for _, test := range []struct {
+		quantity string
+		amount   float64
+		unit     string
+	}{
+		{"100 g", 100, "g"},
+		{"100g", 100, "g"},
+		{"1 xícara (158 g)", 1, "xícara"},
+		{"1 1/2 xícaras (240 g)", 1.5, "xícara"},
+		{"1/2 xícara", 0.5, "xícara"},
+		{"2 colheres de sopa", 2, "colher de sopa"},
+		{"1 colher de chá (5 g)", 1, "colher de chá"},
+		{"2 fatias", 2, "fatia"},
+		{"200 ml", 200, "ml"},
+		{"0,5 l", 0.5, "l"},
+		{"1 porção (30 g)", 1, "porção"},
+		{"1 médio", 1, "unidade média"},
+		{"1 média", 1, "unidade média"},
+		{"2 unidades médias", 2, "unidade média"},
+		{"1 pequena (70 g)", 1, "unidade pequena"},
+		{"3 grandes", 3, "unidade grande"},
+		{"1 unidade", 1, "unidade"},
+		{"1 Concha", 1, "concha"},
+		{"1 bife (120 g)", 1, "bife"},
+		{"a gosto", 0, ""},
+		{"", 0, ""},
+	} {
+		amount, unit := parseServing(test.quantity)
+		if amount != test.amount || unit != test.unit {
+			t.Errorf("parseServing(%q) = %v %q, want %v %q", test.quantity, amount, unit, test.amount, test.unit)
+		}
+	}
+}
+
+func TestApplyFoodMetadata(t *testing.T) {
+	for _, test := range []struct {
+		item     FoodItem
+		foodID   string
+		brand    string
+		servings float64
+	}{
+		{FoodItem{URL: "/calorias-nutrição/genérico/arroz-branco?portionid=55&portionamount=1,500", Quantity: "1 1/2 xícaras"}, "genérico/arroz-branco", "", 1.5},
+		{FoodItem{URL: "/calorias-nutrição/nestlé/iogurte-natural", Quantity: "2 unidades médias"}, "nestlé/iogurte-natural", "nestlé", 2},
+		{FoodItem{URL: "/calorias-nutrição/sadia/peito-de-peru", Quantity: "150 g"}, "sadia/peito-de-peru", "sadia", 1},
+		{FoodItem{URL: "/Diary.aspx?fid=4242", Quantity: ""}, "4242", "", 0},
+	} {
+		item := test.item
+		applyFoodMetadata(&item)
+		if item.FoodID != test.foodID || item.Brand != test.brand || item.Servings != test.servings {
+			t.Errorf("%s: id %q brand %q servings %v, want %q %q %v", test.item.URL, item.FoodID, item.Brand, item.Servings, test.foodID, test.brand, test.servings)
+		}
+	}
+}
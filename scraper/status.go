@@ -0,0 +1,128 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const ScrapeStatusFile = "scrape_status.json"
+
+type UserScrapeStatus struct {
+	Username  string    `json:"username"`
+	Finished  time.Time `json:"finished"`
+	Entries   int       `json:"entries"`
+	Anomalies int       `json:"anomalies"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ScrapeStatus describes the most recent scrape run. Every change is saved to
+// output/scrape_status.json, so the status survives restarts and covers runs
+// started from another process, such as a scheduled scrape.
+type ScrapeStatus struct {
+	Running  bool               `json:"running"`
+	Started  time.Time          `json:"started"`
+	Finished time.Time          `json:"finished"`
+	Users    []UserScrapeStatus `json:"users"`
+}
+
+var (
+	scrapeStatus   = ScrapeStatus{Users: []UserScrapeStatus{}}
+	scrapeStatusMu sync.Mutex
+)
+
+// CurrentScrapeStatus returns the saved status, or the one held by this
+// process when it started a later run or the file can't be read.
+func CurrentScrapeStatus() ScrapeStatus {
+	scrapeStatusMu.Lock()
+	defer scrapeStatusMu.Unlock()
+
+	status := scrapeStatus
+	if saved, err := loadScrapeStatus(); err != nil {
+		fmt.Println(err)
+	} else if saved != nil && saved.Started.After(status.Started) {
+		status = *saved
+	}
+
+	status.Users = append([]UserScrapeStatus{}, status.Users...)
+	return status
+}
+
+func loadScrapeStatus() (*ScrapeStatus, error) {
+	data, err := os.ReadFile(filepath.Join(OutputDir, ScrapeStatusFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scrape status: %v", err)
+	}
+
+	var status ScrapeStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse scrape status: %v", err)
+	}
+
+	return &status, nil
+}
+
+// saveScrapeStatus writes scrapeStatus; callers hold scrapeStatusMu. A failed
+// write is only logged so it never interrupts the scrape.
+func saveScrapeStatus() {
+	if err := os.MkdirAll(OutputDir, 0755); err != nil {
+		fmt.Printf("failed to create output directory: %v\n", err)
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(scrapeStatus, "", "  ")
+	if err != nil {
+		fmt.Printf("failed to marshal scrape status: %v\n", err)
+		return
+	}
+
+	if err := os.WriteFile(filepath.Join(OutputDir, ScrapeStatusFile), jsonData, 0644); err != nil {
+		fmt.Printf("failed to save scrape status: %v\n", err)
+	}
+}
+
+// ErrScrapeRunning is returned when a tracked scrape starts while another
+// one is still running in this process.
+var ErrScrapeRunning = errors.New("a scrape is already running")
+
+// startScrapeStatus begins a new run, or returns false when one is running.
+func startScrapeStatus() bool {
+	scrapeStatusMu.Lock()
+	defer scrapeStatusMu.Unlock()
+
+	if scrapeStatus.Running {
+		return false
+	}
+
+	scrapeStatus = ScrapeStatus{Running: true, Started: time.Now(), Users: []UserScrapeStatus{}}
+	saveScrapeStatus()
+	return true
+}
+
+func recordUserScrape(user User, entries, anomalies int, err error) {
+	scrapeStatusMu.Lock()
+	defer scrapeStatusMu.Unlock()
+
+	status := UserScrapeStatus{Username: user.Username, Finished: time.Now(), Entries: entries, Anomalies: anomalies}
+	if err != nil {
+		status.Error = fmt.Sprintf("%v", err)
+	}
+	scrapeStatus.Users = append(scrapeStatus.Users, status)
+	saveScrapeStatus()
+}
+
+func finishScrapeStatus() {
+	scrapeStatusMu.Lock()
+	defer scrapeStatusMu.Unlock()
+
+	scrapeStatus.Running = false
+	scrapeStatus.Finished = time.Now()
+	saveScrapeStatus()
+}
@@ -0,0 +1,90 @@
+package scraper
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScrapeStatusIsSaved(t *testing.T) {
+	chdirTemp(t)
+
+	startScrapeStatus()
+	recordUserScrape(User{Username: "ana"}, 30, 2, nil)
+	recordUserScrape(User{Username: "bia"}, 0, 0, errors.New("diary page not found"))
+	finishScrapeStatus()
+
+	// A restarted process starts with an empty status.
+	scrapeStatusMu.Lock()
+	scrapeStatus = ScrapeStatus{Users: []UserScrapeStatus{}}
+	scrapeStatusMu.Unlock()
+
+	status := CurrentScrapeStatus()
+	if status.Running || status.Started.IsZero() || status.Finished.IsZero() {
+		t.Errorf("saved status = %+v, want a finished run", status)
+	}
+	if len(status.Users) != 2 || status.Users[0].Anomalies != 2 || status.Users[1].Error != "diary page not found" {
+		t.Errorf("saved users = %+v", status.Users)
+	}
+}
+
+func TestFailedLoginLeavesScrapeStatus(t *testing.T) {
+	chdirTemp(t)
+
+	server := httptest.NewServer((&fakeFatSecret{}).handler())
+	defer server.Close()
+	useFakeSite(t, server)
+
+	users := []User{{Username: "ana", ID: "1"}}
+	if _, err := scrapeUsers("ana@example.com", "wrong", users, true); err == nil {
+		t.Fatal("scrape with a wrong password succeeded")
+	}
+
+	if status := CurrentScrapeStatus(); status.Running {
+		t.Errorf("status after a failed login = %+v, want no running scrape", status)
+	}
+	if _, err := os.Stat(filepath.Join(OutputDir, ScrapeStatusFile)); !os.IsNotExist(err) {
+		t.Error("a failed login saved a scrape status")
+	}
+}
+
+func TestOnDemandScrapeLeavesScrapeStatus(t *testing.T) {
+	chdirTemp(t)
+
+	server := httptest.NewServer((&fakeFatSecret{}).handler())
+	defer server.Close()
+	useFakeSite(t, server)
+
+	date := time.Date(2025, time.May, 15, 0, 0, 0, 0, time.UTC)
+	if _, err := ScrapeFatSecret("ana@example.com", "secret", []User{{Username: "ana", ID: "1"}}, date); err != nil {
+		t.Fatalf("ScrapeFatSecret: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(OutputDir, ScrapeStatusFile)); !os.IsNotExist(err) {
+		t.Error("an on-demand scrape replaced the scrape status")
+	}
+}
+
+func TestOverlappingScrapesAreRejected(t *testing.T) {
+	chdirTemp(t)
+
+	server := httptest.NewServer((&fakeFatSecret{}).handler())
+	defer server.Close()
+	useFakeSite(t, server)
+
+	if !startScrapeStatus() {
+		t.Fatal("could not start a scrape")
+	}
+	defer finishScrapeStatus()
+	started := CurrentScrapeStatus().Started
+
+	users := []User{{Username: "ana", ID: "1"}}
+	if _, err := scrapeUsers("ana@example.com", "secret", users, true); !errors.Is(err, ErrScrapeRunning) {
+		t.Fatalf("second scrape = %v, want ErrScrapeRunning", err)
+	}
+	if status := CurrentScrapeStatus(); !status.Running || !status.Started.Equal(started) {
+		t.Errorf("status = %+v, want the first run still running", status)
+	}
+}
@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+func loadDiaryEntryFile(filename string) (*DiaryEntry, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", filename, err)
+	}
+
+	var file struct {
+		User  User       `json:"user"`
+		Entry DiaryEntry `json:"entry"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", filename, err)
+	}
+
+	return &file.Entry, nil
+}
+
+// ForEachDiaryEntry calls fn for every stored entry of the user between from
+// and to, oldest first, reading one file at a time. Days that were never
+// scraped are skipped.
+func ForEachDiaryEntry(user User, from, to time.Time, fn func(DiaryEntry) error) error {
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		entry, err := loadDiaryEntryFile(diaryEntryFilename(user, date))
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue
+		}
+
+		if err := fn(*entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadDiaryEntries returns the stored entries of the user between from and
+// to, oldest first.
+func LoadDiaryEntries(user User, from, to time.Time) ([]DiaryEntry, error) {
+	entries := []DiaryEntry{}
+
+	err := ForEachDiaryEntry(user, from, to, func(entry DiaryEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
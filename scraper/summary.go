@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type NutrientSummary struct {
+	Name   string `json:"name"`
+	Amount string `json:"amount"`
+	RDI    string `json:"rdi,omitempty"`
+}
+
+// DailySummary holds the user's goal for the day and the nutrient breakdown
+// from the diary's nutrition report. RDI values are percentages of the
+// recommended daily intake as configured on the user's FatSecret profile.
+type DailySummary struct {
+	CalorieGoal string            `json:"calorie_goal"`
+	RDI         string            `json:"rdi"`
+	Nutrients   []NutrientSummary `json:"nutrients"`
+}
+
+var calorieGoalPattern = regexp.MustCompile(`(?i)meta[^0-9]{0,40}([\d.,]+)\s*kcal`)
+
+func extractDailySummary(doc *goquery.Document) DailySummary {
+	summary := DailySummary{}
+
+	if match := calorieGoalPattern.FindStringSubmatch(doc.Text()); match != nil {
+		summary.CalorieGoal = match[1]
+	}
+
+	nutrients := []NutrientSummary{}
+	doc.Find("table.generic tr").Each(func(_ int, s *goquery.Selection) {
+		tds := s.Find("td")
+		if tds.Length() < 2 {
+			return
+		}
+
+		nutrient := NutrientSummary{
+			Name:   strings.TrimSpace(tds.Eq(0).Text()),
+			Amount: strings.TrimSpace(tds.Eq(1).Text()),
+		}
+
+		if tds.Length() >= 3 {
+			rdi := strings.TrimSpace(tds.Eq(2).Text())
+			if strings.HasSuffix(rdi, "%") {
+				nutrient.RDI = rdi
+			}
+		}
+
+		if nutrient.Name == "" || nutrient.Amount == "" || !strings.ContainsAny(nutrient.Amount[:1], "0123456789") {
+			return
+		}
+
+		nutrients = append(nutrients, nutrient)
+	})
+
+	summary.Nutrients = nutrients
+	return summary
+}
+
+// deriveCalorieGoal works the goal back out of the IDR percentage shown on the
+// diary page when the report doesn't state it explicitly.
+func deriveCalorieGoal(calories, idr string) string {
+	percentage := ParseNumber(strings.TrimSuffix(strings.TrimSpace(idr), "%"))
+	if percentage <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f", ParseNumber(calories)*100/percentage)
+}
+
+func getUserDailySummary(client *http.Client, user User, date time.Time) (DailySummary, error) {
+	dateID := convertDateToId(date)
+	reportURL := fmt.Sprintf("%s/Diary.aspx?pa=fjrd&id=%s&dt=%s", baseURL, user.ID, dateID)
+	fmt.Printf("Accessing nutrition report for %s...\n", user.Username)
+
+	resp, err := client.Get(reportURL)
+	if err != nil {
+		return DailySummary{}, err
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return DailySummary{}, err
+	}
+
+	return extractDailySummary(doc), nil
+}
@@ -0,0 +1,73 @@
+package scraper
+
+import "fmt"
+
+const defaultTargetTolerance = 10
+
+// Targets are a user's daily goals. Macros can be given in grams or as a
+// percentage of the calorie target; grams win when both are set. Tolerance
+// is the allowed deviation, in percent, for a day to count as compliant;
+// unset means the default of 10 and 0 means the target must be hit exactly.
+type Targets struct {
+	Calories   float64  `json:"calories,omitempty"`
+	ProteinG   float64  `json:"protein_g,omitempty"`
+	FatG       float64  `json:"fat_g,omitempty"`
+	CarbsG     float64  `json:"carbs_g,omitempty"`
+	ProteinPct float64  `json:"protein_pct,omitempty"`
+	FatPct     float64  `json:"fat_pct,omitempty"`
+	CarbsPct   float64  `json:"carbs_pct,omitempty"`
+	Tolerance  *float64 `json:"tolerance,omitempty"`
+}
+
+func (t Targets) Validate() error {
+	for name, value := range map[string]float64{
+		"calories": t.Calories, "protein_g": t.ProteinG, "fat_g": t.FatG, "carbs_g": t.CarbsG,
+		"protein_pct": t.ProteinPct, "fat_pct": t.FatPct, "carbs_pct": t.CarbsPct, "tolerance": t.TolerancePct(),
+	} {
+		if value < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+
+	if total := t.ProteinPct + t.FatPct + t.CarbsPct; total > 100 {
+		return fmt.Errorf("macro percentages add up to %.0f%%, more than 100%%", total)
+	}
+
+	if (t.ProteinPct > 0 || t.FatPct > 0 || t.CarbsPct > 0) && t.Calories == 0 {
+		return fmt.Errorf("percentage splits need a calorie target")
+	}
+
+	return nil
+}
+
+// TolerancePct returns Tolerance, or the default when it isn't set.
+func (t Targets) TolerancePct() float64 {
+	if t.Tolerance == nil {
+		return defaultTargetTolerance
+	}
+	return *t.Tolerance
+}
+
+// Resolve converts percentage splits into grams (4 kcal/g for protein and
+// carbs, 9 kcal/g for fat) and fills in the default tolerance when none is
+// set.
+func (t Targets) Resolve() Targets {
+	if t.ProteinG == 0 && t.ProteinPct > 0 {
+		t.ProteinG = t.Calories * t.ProteinPct / 100 / 4
+	}
+	if t.CarbsG == 0 && t.CarbsPct > 0 {
+		t.CarbsG = t.Calories * t.CarbsPct / 100 / 4
+	}
+	if t.FatG == 0 && t.FatPct > 0 {
+		t.FatG = t.Calories * t.FatPct / 100 / 9
+	}
+	tolerance := t.TolerancePct()
+	t.Tolerance = &tolerance
+	return t
+}
+
+func UpdateUserTargets(username string, targets Targets) (User, bool, error) {
+	return updateUser(username, func(user *User) {
+		user.Targets = &targets
+	})
+}
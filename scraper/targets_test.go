@@ -0,0 +1,38 @@
+package scraper
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTargetsTolerance(t *testing.T) {
+	for body, want := range map[string]float64{
+		`{"calories": 2000}`:                 defaultTargetTolerance,
+		`{"calories": 2000, "tolerance": 0}`: 0,
+		`{"calories": 2000, "tolerance": 5}`: 5,
+	} {
+		var targets Targets
+		if err := json.Unmarshal([]byte(body), &targets); err != nil {
+			t.Fatal(err)
+		}
+
+		resolved := targets.Resolve()
+		if resolved.Tolerance == nil || *resolved.Tolerance != want {
+			t.Errorf("%s resolved tolerance to %v, want %v", body, resolved.Tolerance, want)
+		}
+	}
+
+	zero := 0.0
+	data, err := json.Marshal(Targets{Calories: 2000, Tolerance: &zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"calories":2000,"tolerance":0}` {
+		t.Errorf("a zero tolerance was saved as %s", data)
+	}
+
+	negative := -1.0
+	if err := (Targets{Calories: 2000, Tolerance: &negative}).Validate(); err == nil {
+		t.Error("Validate accepted a negative tolerance")
+	}
+}
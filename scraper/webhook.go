@@ -0,0 +1,418 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	WebhooksConfigFile    = "webhooks.json"
+	WebhookDeliveriesFile = "webhook_deliveries.json"
+	webhookMaxAttempts    = 3
+	webhookMaxDeliveries  = 500
+)
+
+// WebhookSubscription receives DiaryEvents as signed JSON. Empty Events or
+// Users mean every event type or every user. A secret is generated when none
+// is given; it is only returned when the subscription is created.
+type WebhookSubscription struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+	Users  []string `json:"users"`
+}
+
+type WebhookDelivery struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"subscription_id"`
+	Event          string `json:"event"`
+	Username       string `json:"username"`
+	Date           string `json:"date"`
+	URL            string `json:"url"`
+	Attempts       int    `json:"attempts"`
+	StatusCode     int    `json:"status_code"`
+	Success        bool   `json:"success"`
+	Error          string `json:"error,omitempty"`
+	DeliveredAt    string `json:"delivered_at"`
+}
+
+var (
+	webhooksMu   sync.Mutex
+	deliveriesMu sync.Mutex
+
+	webhookClient = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: webhookDialControl}).DialContext,
+		},
+	}
+	webhookRetryDelay = time.Second
+
+	// pendingWebhooks tracks deliveries started by notifyWebhooks so a
+	// scrape can wait for them before the process exits.
+	pendingWebhooks sync.WaitGroup
+)
+
+// allowPrivateWebhooks reports whether WEBHOOK_ALLOW_PRIVATE is set to a true
+// value, which lets webhooks reach loopback and private network addresses,
+// e.g. a receiver on the same machine during development.
+func allowPrivateWebhooks() bool {
+	allow, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE"))
+	return allow
+}
+
+// blockedWebhookIP reports addresses a webhook must not reach: loopback and
+// private networks unless WEBHOOK_ALLOW_PRIVATE is set, and always
+// link-local (cloud metadata), unspecified and multicast addresses.
+func blockedWebhookIP(ip net.IP) bool {
+	if (ip.IsLoopback() || ip.IsPrivate()) && !allowPrivateWebhooks() {
+		return true
+	}
+	return ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast()
+}
+
+// webhookDialControl checks the address actually dialed, so a host that
+// resolved to a public address when the webhook was registered can't be
+// pointed at the local network later.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || blockedWebhookIP(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+
+	return nil
+}
+
+// ValidateWebhookURL checks that rawURL is http(s) and that its host only
+// resolves to addresses blockedWebhookIP allows.
+func ValidateWebhookURL(rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return fmt.Errorf("a valid http(s) URL is required")
+	}
+
+	ips, err := net.LookupIP(target.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %v", target.Hostname(), err)
+	}
+
+	for _, ip := range ips {
+		if blockedWebhookIP(ip) {
+			return fmt.Errorf("%s resolves to %s, which webhooks are not allowed to reach", target.Hostname(), ip)
+		}
+	}
+
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func loadWebhooks() ([]WebhookSubscription, error) {
+	data, err := os.ReadFile(filepath.Join(ConfigDir, WebhooksConfigFile))
+	if os.IsNotExist(err) {
+		return []WebhookSubscription{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhooks config: %v", err)
+	}
+
+	var subscriptions []WebhookSubscription
+	if err := json.Unmarshal(data, &subscriptions); err != nil {
+		return nil, fmt.Errorf("failed to parse webhooks config: %v", err)
+	}
+
+	return subscriptions, nil
+}
+
+func saveWebhooks(subscriptions []WebhookSubscription) error {
+	if err := os.MkdirAll(ConfigDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	jsonData, err := json.MarshalIndent(subscriptions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhooks: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(ConfigDir, WebhooksConfigFile), jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write webhooks config: %v", err)
+	}
+
+	return nil
+}
+
+func LoadWebhooks() ([]WebhookSubscription, error) {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	return loadWebhooks()
+}
+
+func AddWebhook(subscription WebhookSubscription) (WebhookSubscription, error) {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	subscriptions, err := loadWebhooks()
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	subscription.ID = newID()
+	if subscription.Secret == "" {
+		subscription.Secret = newWebhookSecret()
+	}
+	if subscription.Events == nil {
+		subscription.Events = []string{}
+	}
+	if subscription.Users == nil {
+		subscription.Users = []string{}
+	}
+
+	if err := saveWebhooks(append(subscriptions, subscription)); err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	return subscription, nil
+}
+
+func DeleteWebhook(id string) (bool, error) {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	subscriptions, err := loadWebhooks()
+	if err != nil {
+		return false, err
+	}
+
+	remaining := slices.DeleteFunc(subscriptions, func(s WebhookSubscription) bool {
+		return s.ID == id
+	})
+	if len(remaining) == len(subscriptions) {
+		return false, nil
+	}
+
+	return true, saveWebhooks(remaining)
+}
+
+func LoadWebhookDeliveries() ([]WebhookDelivery, error) {
+	deliveriesMu.Lock()
+	defer deliveriesMu.Unlock()
+
+	return loadWebhookDeliveries()
+}
+
+func loadWebhookDeliveries() ([]WebhookDelivery, error) {
+	data, err := os.ReadFile(filepath.Join(OutputDir, WebhookDeliveriesFile))
+	if os.IsNotExist(err) {
+		return []WebhookDelivery{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook deliveries: %v", err)
+	}
+
+	var deliveries []WebhookDelivery
+	if err := json.Unmarshal(data, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook deliveries: %v", err)
+	}
+
+	return deliveries, nil
+}
+
+// logWebhookDelivery appends to the delivery log, keeping only the most
+// recent webhookMaxDeliveries records.
+func logWebhookDelivery(delivery WebhookDelivery) error {
+	deliveriesMu.Lock()
+	defer deliveriesMu.Unlock()
+
+	deliveries, err := loadWebhookDeliveries()
+	if err != nil {
+		return err
+	}
+
+	deliveries = append(deliveries, delivery)
+	if len(deliveries) > webhookMaxDeliveries {
+		deliveries = deliveries[len(deliveries)-webhookMaxDeliveries:]
+	}
+
+	if err := os.MkdirAll(OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	jsonData, err := json.MarshalIndent(deliveries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook deliveries: %v", err)
+	}
+
+	return os.WriteFile(filepath.Join(OutputDir, WebhookDeliveriesFile), jsonData, 0644)
+}
+
+func (s WebhookSubscription) matches(event DiaryEvent) bool {
+	if event.Type == EventPing {
+		return true
+	}
+	if len(s.Events) > 0 && !slices.Contains(s.Events, event.Type) {
+		return false
+	}
+	if len(s.Users) > 0 && !slices.Contains(s.Users, event.Username) {
+		return false
+	}
+	return true
+}
+
+// SignWebhookPayload returns the value of the X-Signature-256 header: the
+// hex HMAC-SHA256, keyed with the subscription secret, of the
+// X-Webhook-Timestamp value, a dot and the body. Receivers should reject
+// timestamps more than a few minutes old so a captured request can't be
+// replayed.
+func SignWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func postWebhook(subscription WebhookSubscription, deliveryID, eventType string, payload []byte) (int, error) {
+	req, err := http.NewRequest("POST", subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", eventType)
+	req.Header.Set("X-Webhook-Delivery", deliveryID)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Signature-256", SignWebhookPayload(subscription.Secret, timestamp, payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver returned status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// deliverWebhook posts the event, retrying with exponential backoff, and
+// records the outcome in the delivery log.
+func deliverWebhook(subscription WebhookSubscription, event DiaryEvent) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:             newID(),
+		SubscriptionID: subscription.ID,
+		Event:          event.Type,
+		Username:       event.Username,
+		Date:           event.Date,
+		URL:            subscription.URL,
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		delivery.Error = fmt.Sprintf("failed to marshal event: %v", err)
+	} else {
+		delay := webhookRetryDelay
+		for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+			delivery.Attempts = attempt
+			delivery.StatusCode, err = postWebhook(subscription, delivery.ID, event.Type, payload)
+			if err == nil {
+				delivery.Success = true
+				delivery.Error = ""
+				break
+			}
+
+			delivery.Error = err.Error()
+			if attempt < webhookMaxAttempts {
+				time.Sleep(delay)
+				delay *= 2
+			}
+		}
+	}
+
+	delivery.DeliveredAt = time.Now().UTC().Format(time.RFC3339)
+
+	if err := logWebhookDelivery(delivery); err != nil {
+		fmt.Printf("Error logging webhook delivery %s: %v\n", delivery.ID, err)
+	}
+
+	return delivery
+}
+
+func notifyWebhooks(event DiaryEvent) {
+	subscriptions, err := LoadWebhooks()
+	if err != nil {
+		fmt.Printf("Error loading webhooks: %v\n", err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		if subscription.matches(event) {
+			pendingWebhooks.Add(1)
+			go func(subscription WebhookSubscription) {
+				defer pendingWebhooks.Done()
+				deliverWebhook(subscription, event)
+			}(subscription)
+		}
+	}
+}
+
+// FlushWebhooks waits until every delivery started so far, retries included,
+// has finished.
+func FlushWebhooks() {
+	pendingWebhooks.Wait()
+}
+
+// PingWebhook sends a ping event to a single subscription synchronously so a
+// receiver can be checked end to end.
+func PingWebhook(id string) (WebhookDelivery, bool, error) {
+	subscriptions, err := LoadWebhooks()
+	if err != nil {
+		return WebhookDelivery{}, false, err
+	}
+
+	for _, subscription := range subscriptions {
+		if subscription.ID == id {
+			event := DiaryEvent{
+				Type:       EventPing,
+				OccurredAt: time.Now().UTC().Format(time.RFC3339),
+			}
+			return deliverWebhook(subscription, event), true, nil
+		}
+	}
+
+	return WebhookDelivery{}, false, nil
+}
@@ -0,0 +1,210 @@
+package scraper
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useWebhookReceiver lets deliveries reach a local receiver through
+// WEBHOOK_ALLOW_PRIVATE, as during development, and keeps retries fast.
+func useWebhookReceiver(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "true")
+	previousDelay := webhookRetryDelay
+	webhookRetryDelay = time.Millisecond
+	t.Cleanup(func() { webhookRetryDelay = previousDelay })
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	chdirTemp(t)
+
+	var (
+		mu       sync.Mutex
+		received []receivedWebhook
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		received = append(received, receivedWebhook{header: r.Header.Clone(), body: body})
+		attempt := len(received)
+		mu.Unlock()
+
+		if attempt < webhookMaxAttempts {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	useWebhookReceiver(t)
+
+	subscription, err := AddWebhook(WebhookSubscription{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscription.Secret) != 64 {
+		t.Fatalf("generated secret %q, want 32 random bytes in hex", subscription.Secret)
+	}
+
+	event := DiaryEvent{
+		Type:       EventDiaryUpdated,
+		Username:   "ana",
+		Date:       "15/05/2025",
+		Version:    2,
+		OccurredAt: "2025-05-15T12:00:00Z",
+		Entry:      DiaryEntry{Date: "15/05/2025", Calories: "1.845"},
+	}
+	delivery := deliverWebhook(subscription, event)
+
+	if !delivery.Success || delivery.Attempts != webhookMaxAttempts || delivery.StatusCode != http.StatusNoContent {
+		t.Fatalf("delivery = %+v, want success on attempt %d", delivery, webhookMaxAttempts)
+	}
+	if len(received) != webhookMaxAttempts {
+		t.Fatalf("receiver got %d requests, want %d", len(received), webhookMaxAttempts)
+	}
+
+	for _, request := range received {
+		timestamp := request.header.Get("X-Webhook-Timestamp")
+		sent, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+			t.Errorf("X-Webhook-Timestamp = %q, want the current unix time", timestamp)
+		}
+
+		if got, want := request.header.Get("X-Signature-256"), SignWebhookPayload(subscription.Secret, timestamp, request.body); got != want {
+			t.Errorf("X-Signature-256 = %q, want %q", got, want)
+		}
+		if got := request.header.Get("X-Webhook-Delivery"); got != delivery.ID {
+			t.Errorf("X-Webhook-Delivery = %q, want %q", got, delivery.ID)
+		}
+
+		var payload DiaryEvent
+		if err := json.Unmarshal(request.body, &payload); err != nil {
+			t.Fatalf("payload is not a DiaryEvent: %v", err)
+		}
+		if payload.Type != EventDiaryUpdated || payload.Username != "ana" || payload.Version != 2 || payload.Entry.Calories != "1.845" {
+			t.Errorf("payload = %+v, want the delivered event", payload)
+		}
+	}
+
+	if SignWebhookPayload(subscription.Secret, "0", received[0].body) == received[0].header.Get("X-Signature-256") {
+		t.Error("signature does not cover the timestamp")
+	}
+
+	deliveries, err := LoadWebhookDeliveries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].ID != delivery.ID {
+		t.Errorf("delivery log = %+v, want the one delivery", deliveries)
+	}
+}
+
+func TestWebhookClientRefusesLoopback(t *testing.T) {
+	chdirTemp(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("default webhook client reached a loopback receiver")
+	}))
+	defer server.Close()
+
+	previousDelay := webhookRetryDelay
+	webhookRetryDelay = time.Millisecond
+	defer func() { webhookRetryDelay = previousDelay }()
+
+	delivery := deliverWebhook(WebhookSubscription{ID: "1", URL: server.URL, Secret: "s"}, DiaryEvent{Type: EventPing})
+	if delivery.Success {
+		t.Error("delivery to a loopback address succeeded")
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for _, rawURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+		"ftp://93.184.216.34/hook",
+		"not a url",
+	} {
+		if err := ValidateWebhookURL(rawURL); err == nil {
+			t.Errorf("ValidateWebhookURL(%q) accepted it", rawURL)
+		}
+	}
+
+	if err := ValidateWebhookURL("https://93.184.216.34/hook"); err != nil {
+		t.Errorf("ValidateWebhookURL rejected a public address: %v", err)
+	}
+}
+
+func TestValidateWebhookURLAllowPrivate(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "true")
+
+	for _, rawURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.10/hook",
+	} {
+		if err := ValidateWebhookURL(rawURL); err != nil {
+			t.Errorf("ValidateWebhookURL(%q) with WEBHOOK_ALLOW_PRIVATE: %v", rawURL, err)
+		}
+	}
+
+	for _, rawURL := range []string{
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+	} {
+		if err := ValidateWebhookURL(rawURL); err == nil {
+			t.Errorf("ValidateWebhookURL(%q) accepted it with WEBHOOK_ALLOW_PRIVATE", rawURL)
+		}
+	}
+}
+
+func TestFlushWebhooks(t *testing.T) {
+	chdirTemp(t)
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		received = append(received, r.Header.Get("X-Webhook-Event"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	useWebhookReceiver(t)
+
+	for _, events := range [][]string{{EventDiaryCreated}, {EventDiaryUpdated}, {}} {
+		if _, err := AddWebhook(WebhookSubscription{URL: server.URL, Events: events}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifyWebhooks(DiaryEvent{Type: EventDiaryCreated, Username: "ana", Date: "15/05/2025"})
+	FlushWebhooks()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Errorf("receiver got %v after FlushWebhooks, want the two matching deliveries", received)
+	}
+}
@@ -0,0 +1,304 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type WeightEntry struct {
+	Date   string `json:"date"`
+	Weight string `json:"weight"`
+	Note   string `json:"note"`
+}
+
+var portugueseMonths = map[string]time.Month{
+	"janeiro":   time.January,
+	"fevereiro": time.February,
+	"março":     time.March,
+	"abril":     time.April,
+	"maio":      time.May,
+	"junho":     time.June,
+	"julho":     time.July,
+	"agosto":    time.August,
+	"setembro":  time.September,
+	"outubro":   time.October,
+	"novembro":  time.November,
+	"dezembro":  time.December,
+}
+
+// parseDiaryDate accepts both the numeric format used in our files and the
+// long Portuguese format shown on the site ("segunda-feira, 24 de março de 2025").
+func parseDiaryDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if date, err := time.Parse("02/01/2006", value); err == nil {
+		return date, nil
+	}
+
+	if idx := strings.Index(value, ","); idx >= 0 {
+		value = strings.TrimSpace(value[idx+1:])
+	}
+
+	var day, year int
+	var month string
+	if _, err := fmt.Sscanf(strings.ReplaceAll(value, " de ", " "), "%d %s %d", &day, &month, &year); err != nil {
+		return time.Time{}, fmt.Errorf("unrecognized date %q", value)
+	}
+
+	m, ok := portugueseMonths[strings.ToLower(month)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unrecognized month %q", month)
+	}
+
+	return time.Date(year, m, day, 0, 0, 0, 0, time.UTC), nil
+}
+
+func extractWeightEntries(doc *goquery.Document) []WeightEntry {
+	entries := []WeightEntry{}
+
+	doc.Find("table.generic tr").Each(func(_ int, s *goquery.Selection) {
+		tds := s.Find("td")
+		if tds.Length() < 2 {
+			return
+		}
+
+		date, err := parseDiaryDate(tds.Eq(0).Text())
+		if err != nil {
+			return
+		}
+
+		entry := WeightEntry{
+			Date:   date.Format("02/01/2006"),
+			Weight: strings.TrimSpace(tds.Eq(1).Text()),
+		}
+
+		if tds.Length() >= 3 {
+			entry.Note = strings.TrimSpace(tds.Eq(2).Text())
+		}
+
+		if entry.Weight != "" {
+			entries = append(entries, entry)
+		}
+	})
+
+	return entries
+}
+
+func getUserWeightMonth(client *http.Client, user User, month time.Time) ([]WeightEntry, error) {
+	dateID := convertDateToId(month)
+	weightURL := fmt.Sprintf("%s/Diary.aspx?pa=wj&id=%s&dt=%s", baseURL, user.ID, dateID)
+	fmt.Printf("Accessing weight journal for %s (%s)...\n", user.Username, month.Format("01/2006"))
+
+	resp, err := client.Get(weightURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractWeightEntries(doc), nil
+}
+
+func weightHistoryFilename(user User) string {
+	return filepath.Join(OutputDir, fmt.Sprintf("%s_weight.json", user.Username))
+}
+
+func LoadWeightHistory(user User) ([]WeightEntry, error) {
+	data, err := os.ReadFile(weightHistoryFilename(user))
+	if os.IsNotExist(err) {
+		return []WeightEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read weight history for %s: %v", user.Username, err)
+	}
+
+	var entries []WeightEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse weight history for %s: %v", user.Username, err)
+	}
+
+	return entries, nil
+}
+
+func saveWeightHistory(user User, entries []WeightEntry) error {
+	if err := os.MkdirAll(OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	jsonData, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal weight history for %s: %v", user.Username, err)
+	}
+
+	filename := weightHistoryFilename(user)
+	if err := os.WriteFile(filename, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write weight history for %s: %v", user.Username, err)
+	}
+
+	fmt.Printf("Saved weight history for %s to %s\n", user.Username, filename)
+	return nil
+}
+
+func mergeWeightEntries(existing, scraped []WeightEntry) []WeightEntry {
+	byDate := make(map[string]WeightEntry)
+	for _, entry := range existing {
+		byDate[entry.Date] = entry
+	}
+	for _, entry := range scraped {
+		byDate[entry.Date] = entry
+	}
+
+	merged := make([]WeightEntry, 0, len(byDate))
+	for _, entry := range byDate {
+		merged = append(merged, entry)
+	}
+
+	slices.SortFunc(merged, func(a, b WeightEntry) int {
+		aDate, _ := time.Parse("02/01/2006", a.Date)
+		bDate, _ := time.Parse("02/01/2006", b.Date)
+		return aDate.Compare(bDate)
+	})
+
+	return merged
+}
+
+func filterWeightEntries(entries []WeightEntry, from, to time.Time) []WeightEntry {
+	filtered := []WeightEntry{}
+	for _, entry := range entries {
+		date, err := time.Parse("02/01/2006", entry.Date)
+		if err != nil || date.Before(from) || date.After(to) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// MaxWeightHistoryMonths caps how many months one ScrapeWeightHistory call
+// may cover, since every month not cached yet is a separate page.
+const MaxWeightHistoryMonths = 24
+
+func weightMonthsFilename(user User) string {
+	return filepath.Join(OutputDir, fmt.Sprintf("%s_weight_months.json", user.Username))
+}
+
+// loadScrapedWeightMonths returns the months, as YYYY-MM, that were scraped
+// after they ended and so can't change anymore.
+func loadScrapedWeightMonths(user User) (map[string]bool, error) {
+	months := make(map[string]bool)
+
+	data, err := os.ReadFile(weightMonthsFilename(user))
+	if os.IsNotExist(err) {
+		return months, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read weight months for %s: %v", user.Username, err)
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse weight months for %s: %v", user.Username, err)
+	}
+	for _, month := range list {
+		months[month] = true
+	}
+
+	return months, nil
+}
+
+func saveScrapedWeightMonths(user User, months map[string]bool) error {
+	list := []string{}
+	for month := range months {
+		list = append(list, month)
+	}
+	slices.Sort(list)
+
+	jsonData, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal weight months for %s: %v", user.Username, err)
+	}
+
+	if err := os.WriteFile(weightMonthsFilename(user), jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write weight months for %s: %v", user.Username, err)
+	}
+
+	return nil
+}
+
+// WeightHistoryMonths returns how many calendar months from and to span.
+func WeightHistoryMonths(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month()) + 1
+}
+
+// ScrapeWeightHistory scrapes every month of the weight journal between from
+// and to, merges the weigh-ins into the user's stored history and returns the
+// entries within the range, oldest first. Months that were scraped after they
+// ended are read from the stored history instead, and no login happens when
+// every month is cached.
+func ScrapeWeightHistory(username, password string, user User, from, to time.Time) ([]WeightEntry, error) {
+	if months := WeightHistoryMonths(from, to); months > MaxWeightHistoryMonths {
+		return nil, fmt.Errorf("range covers %d months, at most %d are allowed", months, MaxWeightHistoryMonths)
+	}
+
+	cached, err := loadScrapedWeightMonths(user)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []time.Time{}
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(to); month = month.AddDate(0, 1, 0) {
+		if !cached[month.Format("2006-01")] {
+			pending = append(pending, month)
+		}
+	}
+
+	existing, err := LoadWeightHistory(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) == 0 {
+		return filterWeightEntries(existing, from, to), nil
+	}
+
+	client, err := loginToFatSecret(username, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to login: %v", err)
+	}
+
+	scraped := []WeightEntry{}
+	now := time.Now().UTC()
+	for _, month := range pending {
+		entries, err := getUserWeightMonth(client, user, month)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get weight journal for %s: %v", user.Username, err)
+		}
+		scraped = append(scraped, entries...)
+
+		if now.After(month.AddDate(0, 1, 0)) {
+			cached[month.Format("2006-01")] = true
+		}
+	}
+
+	merged := mergeWeightEntries(existing, scraped)
+	if err := saveWeightHistory(user, merged); err != nil {
+		return nil, err
+	}
+	if err := saveScrapedWeightMonths(user, cached); err != nil {
+		return nil, err
+	}
+
+	return filterWeightEntries(merged, from, to), nil
+}
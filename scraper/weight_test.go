@@ -0,0 +1,130 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseDiaryDate(t *testing.T) {
+	for value, want := range map[string]time.Time{
+		"24/03/2025":                           time.Date(2025, time.March, 24, 0, 0, 0, 0, time.UTC),
+		"segunda-feira, 24 de março de 2025":   time.Date(2025, time.March, 24, 0, 0, 0, 0, time.UTC),
+		"  sábado, 1 de Fevereiro de 2025 ":    time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC),
+		"quarta-feira, 31 de dezembro de 2025": time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC),
+	} {
+		got, err := parseDiaryDate(value)
+		if err != nil {
+			t.Errorf("parseDiaryDate(%q): %v", value, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("parseDiaryDate(%q) = %s, want %s", value, got, want)
+		}
+	}
+
+	for _, value := range []string{"", "Data", "24 de marzo de 2025", "2025-03-24"} {
+		if _, err := parseDiaryDate(value); err == nil {
+			t.Errorf("parseDiaryDate(%q) accepted an unknown date", value)
+		}
+	}
+}
+
+// weightPage renders a weight journal month with one row per weigh-in.
+func weightPage(rows ...[3]string) string {
+	page := `<html><body><table class="generic"><tr><th>Data</th><th>Peso</th><th>Nota</th></tr>`
+	for _, row := range rows {
+		page += fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%s</td></tr>", row[0], row[1], row[2])
+	}
+	return page + `<tr><td colspan="3">Média do mês</td></tr></table></body></html>`
+}
+
+func TestExtractWeightEntries(t *testing.T) {
+	doc := parseFixture(t, weightPage(
+		[3]string{"segunda-feira, 3 de março de 2025", "82,4 kg", " depois do treino "},
+		[3]string{"quarta-feira, 5 de março de 2025", "", ""},
+		[3]string{"sexta-feira, 7 de março de 2025", "81,9 kg", ""},
+	))
+
+	want := []WeightEntry{
+		{Date: "03/03/2025", Weight: "82,4 kg", Note: "depois do treino"},
+		{Date: "07/03/2025", Weight: "81,9 kg"},
+	}
+	if got := extractWeightEntries(doc); !slices.Equal(got, want) {
+		t.Errorf("extractWeightEntries = %+v, want %+v", got, want)
+	}
+}
+
+func TestScrapeWeightHistoryCachesFinishedMonths(t *testing.T) {
+	chdirTemp(t)
+
+	march := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+	pages := map[string]string{
+		convertDateToId(march): weightPage([3]string{"segunda-feira, 3 de março de 2025", "82,4 kg", ""}),
+		convertDateToId(april): weightPage([3]string{"terça-feira, 1 de abril de 2025", "81,7 kg", ""}),
+	}
+
+	var mu sync.Mutex
+	requested := []string{}
+
+	fake := &fakeFatSecret{}
+	fake.journal = func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pa") != "wj" || r.URL.Query().Get("id") != "1" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		requested = append(requested, r.URL.Query().Get("dt"))
+		mu.Unlock()
+		fmt.Fprint(w, pages[r.URL.Query().Get("dt")])
+	}
+	server := httptest.NewServer(fake.handler())
+	defer server.Close()
+	useFakeSite(t, server)
+
+	user := User{Username: "ana", ID: "1"}
+	to := april.AddDate(0, 1, -1)
+	want := []WeightEntry{{Date: "03/03/2025", Weight: "82,4 kg"}, {Date: "01/04/2025", Weight: "81,7 kg"}}
+
+	entries, err := ScrapeWeightHistory("ana@example.com", "secret", user, march, to)
+	if err != nil {
+		t.Fatalf("ScrapeWeightHistory: %v", err)
+	}
+	if !slices.Equal(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+	if len(requested) != 2 {
+		t.Errorf("requested %d months, want 2", len(requested))
+	}
+
+	data, err := os.ReadFile(weightMonthsFilename(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[\n  \"2025-03\",\n  \"2025-04\"\n]" {
+		t.Errorf("cached months = %s", data)
+	}
+
+	// Both months ended before they were scraped, so the second call neither
+	// logs in nor fetches a page.
+	entries, err = ScrapeWeightHistory("ana@example.com", "wrong password", user, march, to)
+	if err != nil {
+		t.Fatalf("ScrapeWeightHistory from cache: %v", err)
+	}
+	if !slices.Equal(entries, want) {
+		t.Errorf("cached entries = %+v, want %+v", entries, want)
+	}
+	if len(requested) != 2 {
+		t.Errorf("the cached months were fetched again: %v", requested)
+	}
+
+	if _, err := ScrapeWeightHistory("ana@example.com", "secret", user, march, march.AddDate(0, MaxWeightHistoryMonths, 0)); err == nil {
+		t.Error("ScrapeWeightHistory accepted a range over MaxWeightHistoryMonths")
+	}
+}